@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/codegangsta/cli"
+	"github.com/joefitzgerald/inductor/configuration"
+	"github.com/joefitzgerald/inductor/iso"
+)
+
+func checksumCommand() cli.Command {
+	return cli.Command{
+		Name:      "checksum",
+		Usage:     "Hash the local ISO of an operating system and compare it with the configured checksum",
+		ArgsUsage: "<os>",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "iso",
+				Usage: "The ISO file to hash (default: the first local ISO URL which exists)",
+			},
+			cli.BoolFlag{
+				Name:  "update",
+				Usage: "Write the computed checksum to the configuration file",
+			},
+		},
+		Action: checksum,
+	}
+}
+
+func checksum(c *cli.Context) error {
+	osname, err := osNameArg(c)
+	if err != nil {
+		return exitError(err)
+	}
+	layers, err := loadLayers(c)
+	if err != nil {
+		return exitError(err)
+	}
+	config, err := layers.Configuration()
+	if err != nil {
+		return exitError(err)
+	}
+	osConfig, ok := config.Get(osname)
+	if !ok {
+		return exitError(fmt.Errorf("Couldn't find OS configuration for '%s'", osname))
+	}
+	if len(osConfig.IsoChecksumType) == 0 {
+		return exitError(fmt.Errorf("No iso_checksum_type is configured for '%s'", osname))
+	}
+
+	isoPath := c.String("iso")
+	if len(isoPath) == 0 {
+		if isoPath, ok = iso.FindLocal(osConfig.AllIsoURLs()); !ok {
+			return exitError(fmt.Errorf("Couldn't find a local ISO for '%s', use --iso to hash a downloaded ISO", osname))
+		}
+	}
+	computed, err := iso.FileChecksum(isoPath, osConfig.IsoChecksumType)
+	if err != nil {
+		return exitError(err)
+	}
+
+	fmt.Printf("ISO:       %s\n", isoPath)
+	fmt.Printf("Computed:  %s %s\n", osConfig.IsoChecksumType, computed)
+	fmt.Printf("Expected:  %s %s\n", osConfig.IsoChecksumType, osConfig.IsoChecksum)
+	if iso.ChecksumEqual(computed, osConfig.IsoChecksum) {
+		fmt.Println("Checksum matches")
+		return nil
+	}
+
+	if !c.Bool("update") {
+		return exitError(fmt.Errorf("The checksum of %s doesn't match the iso_checksum of '%s', run with --update to write the computed checksum", isoPath, osname))
+	}
+	path, err := updateChecksum(c, layers, osname, computed)
+	if err != nil {
+		return exitError(err)
+	}
+	fmt.Printf("Updated the iso_checksum of '%s' in %s\n", osname, path)
+	return nil
+}
+
+// updateChecksum writes the checksum to the configuration file which sets the
+// OS checksum, or the last file which configures the OS
+func updateChecksum(c *cli.Context, layers *configuration.Layers, osname, checksum string) (string, error) {
+	keys := []string{"operating_systems", osname, "iso_checksum"}
+	path, ok := layers.Source(keys...)
+	if !ok {
+		path, _ = layers.Source(keys[:2]...)
+	}
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	updated, err := configuration.SetString(src, configFormat(c, path), keys, checksum)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return path, ioutil.WriteFile(path, updated, info.Mode())
+}
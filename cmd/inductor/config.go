@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+)
+
+func configCommand() cli.Command {
+	return cli.Command{
+		Name:  "config",
+		Usage: "Inspect the merged configuration",
+		Subcommands: []cli.Command{
+			{
+				Name:   "print",
+				Usage:  "Print every effective configuration value, after interpolating environment variables, resolving extends and applying defaults, and the file it came from",
+				Action: configPrint,
+			},
+		},
+	}
+}
+
+func configPrint(c *cli.Context) error {
+	layers, err := loadLayers(c)
+	if err != nil {
+		return exitError(err)
+	}
+
+	values, err := layers.Values()
+	if err != nil {
+		return exitError(err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, v := range values {
+		val, err := json.Marshal(v.Value)
+		if err != nil {
+			return exitError(err)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", v.Path, v.Source, val)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/codegangsta/cli"
+	"github.com/joefitzgerald/inductor/cpy"
+	"github.com/joefitzgerald/inductor/output"
+	"github.com/joefitzgerald/inductor/renderer"
+	"github.com/joefitzgerald/inductor/tpl"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+func diffCommand() cli.Command {
+	return cli.Command{
+		Name:      "diff",
+		Usage:     "Diff freshly rendered templates against the output directory",
+		ArgsUsage: "<os>",
+		Flags:     renderOptionFlags(),
+		Action:    diff,
+	}
+}
+
+func diff(c *cli.Context) error {
+	osname, err := osNameArg(c)
+	if err != nil {
+		return exitError(err)
+	}
+	config, err := loadConfiguration(c)
+	if err != nil {
+		return exitError(err)
+	}
+	opts, err := createRenderOpts(c, osname, c.String("edition"), config)
+	if err != nil {
+		return exitError(err)
+	}
+	outDir, err := outDir(c, config)
+	if err != nil {
+		return exitError(err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return exitError(err)
+	}
+
+	// render all the templates into memory
+	copies, err := cpy.New(nil).Files(cwd, outDir)
+	if err != nil {
+		return exitError(err)
+	}
+	rendered := output.NewMemory()
+	r := renderer.NewWithCopies(opts, rendered, copies)
+	if err = r.Render(tpl.New(cwd, opts.OSName)); err != nil {
+		return exitError(err)
+	}
+
+	changed := 0
+	for _, name := range rendered.Names() {
+		content, _ := rendered.File(name)
+		d, err := unifiedDiff(outDir, name, string(content))
+		if err != nil {
+			return exitError(err)
+		}
+		if len(d) > 0 {
+			changed++
+			fmt.Print(opts.Redact(d))
+		}
+	}
+
+	if changed > 0 {
+		return exitError(fmt.Errorf("%d rendered file(s) differ from %s", changed, outDir))
+	}
+	return nil
+}
+
+// unifiedDiff diffs the existing file in the output dir against the newly
+// rendered content, a missing file is diffed as empty
+func unifiedDiff(outDir, name, content string) (string, error) {
+	fromFile := "a/" + name
+	existing, err := ioutil.ReadFile(filepath.Join(outDir, filepath.FromSlash(name)))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		fromFile = "/dev/null"
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(existing)),
+		B:        difflib.SplitLines(content),
+		FromFile: fromFile,
+		ToFile:   "b/" + name,
+		Context:  3,
+	})
+}
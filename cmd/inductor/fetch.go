@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/codegangsta/cli"
+	"github.com/joefitzgerald/inductor/iso"
+)
+
+func fetchCommand() cli.Command {
+	return cli.Command{
+		Name:      "fetch",
+		Usage:     "Download the ISO of an operating system into the cache, render with --use-cache to use it",
+		ArgsUsage: "<os>",
+		Action:    fetch,
+	}
+}
+
+func cacheCommand() cli.Command {
+	return cli.Command{
+		Name:  "cache",
+		Usage: "Manage the ISO cache",
+		Subcommands: []cli.Command{
+			{
+				Name:  "prune",
+				Usage: "Remove cached ISOs which haven't been used recently",
+				Flags: []cli.Flag{
+					cli.DurationFlag{
+						Name:  "max-age",
+						Usage: "Remove ISOs which haven't been fetched or used for longer than the duration, e.g. 720h",
+					},
+					cli.StringFlag{
+						Name:  "max-size",
+						Usage: "Remove the least recently used ISOs until the cache is no larger than the size, e.g. 50GB",
+					},
+				},
+				Action: cachePrune,
+			},
+		},
+	}
+}
+
+func fetch(c *cli.Context) error {
+	osname, err := osNameArg(c)
+	if err != nil {
+		return exitError(err)
+	}
+	config, err := loadConfiguration(c)
+	if err != nil {
+		return exitError(err)
+	}
+	osConfig, ok := config.Get(osname)
+	if !ok {
+		return exitError(fmt.Errorf("Couldn't find OS configuration for '%s'", osname))
+	}
+	if len(osConfig.IsoChecksum) == 0 {
+		return exitError(fmt.Errorf("An iso_checksum is required to fetch the ISO for '%s'", osname))
+	}
+
+	cache, err := isoCache(c)
+	if err != nil {
+		return exitError(err)
+	}
+	path, err := cache.Fetch(osConfig.AllIsoURLs(), osConfig.IsoChecksumType, osConfig.IsoChecksum)
+	if err != nil {
+		return exitError(err)
+	}
+	fmt.Println(path)
+	return nil
+}
+
+func cachePrune(c *cli.Context) error {
+	maxSize, err := parseSize(c.String("max-size"))
+	if err != nil {
+		return exitError(err)
+	}
+	if c.Duration("max-age") == 0 && maxSize == 0 {
+		return exitError(errors.New("You must specify a --max-age or --max-size"))
+	}
+	cache, err := isoCache(c)
+	if err != nil {
+		return exitError(err)
+	}
+	removed, err := cache.Prune(c.Duration("max-age"), maxSize)
+	for _, path := range removed {
+		fmt.Printf("Removed %s\n", path)
+	}
+	return exitError(err)
+}
+
+// isoCache is the ISO cache in the --cache-dir, defaulting to the XDG cache
+// directory
+func isoCache(c *cli.Context) (*iso.Cache, error) {
+	dir := c.GlobalString("cache-dir")
+	if len(dir) == 0 {
+		dir = xdgDir("XDG_CACHE_HOME", ".cache")
+		if len(dir) == 0 {
+			return nil, errors.New("Couldn't find the home directory, use --cache-dir to set the ISO cache directory")
+		}
+		dir = filepath.Join(dir, "inductor", "isos")
+	}
+	return iso.NewCache(dir), nil
+}
+
+// sizeUnits are the binary multiples of the units sizes may be given in
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// parseSize parses a size like 50GB, an empty size is 0
+func parseSize(s string) (int64, error) {
+	if len(s) == 0 {
+		return 0, nil
+	}
+	size := strings.ToUpper(strings.TrimSpace(s))
+	multiple := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(size, unit.suffix) {
+			size = strings.TrimSpace(strings.TrimSuffix(size, unit.suffix))
+			multiple = unit.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(size, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("Invalid size '%s', expected a number of bytes with an optional KB, MB, GB or TB unit", s)
+	}
+	return int64(n * float64(multiple)), nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+	"github.com/joefitzgerald/inductor/iso"
+)
+
+func inspectISOCommand() cli.Command {
+	return cli.Command{
+		Name:      "inspect-iso",
+		Usage:     "List the Windows images of an ISO's install.wim or install.esd",
+		ArgsUsage: "<path>",
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "editions",
+				Usage: "Print the images as editions entries for the OS configuration",
+			},
+		},
+		Action: inspectISO,
+	}
+}
+
+func inspectISO(c *cli.Context) error {
+	if len(c.Args()) == 0 {
+		return exitError(errors.New("You must specify the path of an ISO, WIM or ESD file"))
+	}
+	images, err := readImages(c.Args()[0])
+	if err != nil {
+		return exitError(err)
+	}
+	if c.Bool("editions") {
+		return printEditions(images)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "Index\tName\tArchitecture\tLanguages\tVersion")
+	for _, img := range images {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", img.Index, img.Name, img.Architecture, strings.Join(img.Languages, ", "), img.Version)
+	}
+	return w.Flush()
+}
+
+// readImages reads the images of an ISO, or a WIM or ESD file
+func readImages(path string) ([]iso.WIMImage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".wim", ".esd":
+		return iso.WIMImages(f)
+	}
+	return iso.InstallImages(f)
+}
+
+// printEditions prints the images as ready to paste editions entries, the
+// image name is used for the windows_image_name as it's unique per image
+func printEditions(images []iso.WIMImage) error {
+	editions := make(map[string]map[string]string)
+	for _, img := range images {
+		name := img.EditionName()
+		if _, ok := editions[name]; ok {
+			name = fmt.Sprintf("%s%d", name, img.Index)
+		}
+		editions[name] = map[string]string{"windows_image_name": img.Name}
+	}
+	out, err := json.MarshalIndent(map[string]interface{}{"editions": editions}, "", "  ")
+	if err != nil {
+		return err
+	}
+	// strip the outer braces so the entries can be pasted into an OS
+	lines := strings.Split(string(out), "\n")
+	for _, line := range lines[1 : len(lines)-1] {
+		fmt.Println(strings.TrimPrefix(line, "  "))
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/codegangsta/cli"
+)
+
+func listCommand() cli.Command {
+	return cli.Command{
+		Name:   "list",
+		Usage:  "List the available operating systems",
+		Action: list,
+	}
+}
+
+func list(c *cli.Context) error {
+	config, err := loadConfiguration(c)
+	if err != nil {
+		return exitError(err)
+	}
+	for _, s := range config.List() {
+		fmt.Println(s)
+	}
+	return nil
+}
@@ -8,9 +8,6 @@ import (
 
 	"github.com/codegangsta/cli"
 	"github.com/joefitzgerald/inductor/configuration"
-	"github.com/joefitzgerald/inductor/cpy"
-	"github.com/joefitzgerald/inductor/renderer"
-	"github.com/joefitzgerald/inductor/tpl"
 )
 
 // Version of the CLI
@@ -30,130 +27,137 @@ func newApp() *cli.App {
 	app.Usage = "Generate Packer Templates"
 	app.Version = Version
 	app.Flags = []cli.Flag{
-		cli.StringFlag{
-			Name:  "config, c",
-			Value: "inductor.json",
-			Usage: "Inductor configuration source file",
-		},
-		cli.StringFlag{
-			Name:  "outdir, o",
-			Value: "out",
-			Usage: "The root output directory for all rendered templates",
+		cli.StringSliceFlag{
+			Name:   "config, c",
+			EnvVar: "INDUCTOR_CONFIG",
+			Usage:  "Inductor configuration source file, may be repeated to merge files in order (default: inductor.json, the user config and inductor.local.json)",
 		},
 		cli.StringFlag{
-			Name:  "edition, e",
-			Usage: "The optional operating system edition",
+			Name:   "config-format",
+			EnvVar: "INDUCTOR_CONFIG_FORMAT",
+			Usage:  "The configuration format, one of json, yaml or toml (default: based on the config file extension)",
 		},
 		cli.StringFlag{
-			Name:  "productkey, k",
-			Usage: "The MS Windows product key if you have one",
-		},
-		cli.BoolFlag{
-			Name:  "skipwindowsupdates, u",
-			Usage: "Skips running Windows updates on first boot",
-		},
-		cli.BoolFlag{
-			Name:  "ssh, s",
-			Usage: "Uses the Packer SSH communicator instead of the default WinRM",
-		},
-		cli.BoolFlag{
-			Name:  "gui, g",
-			Usage: "Run the VM with a GUI",
+			Name:   "cache-dir",
+			EnvVar: "INDUCTOR_CACHE_DIR",
+			Usage:  "The directory ISOs are fetched into (default: ~/.cache/inductor/isos)",
 		},
 	}
-	app.Action = run
+	app.Commands = []cli.Command{
+		listCommand(),
+		showCommand(),
+		renderCommand(),
+		diffCommand(),
+		validateCommand(),
+		configCommand(),
+		checksumCommand(),
+		fetchCommand(),
+		cacheCommand(),
+		inspectISOCommand(),
+	}
 	return app
 }
 
-func run(c *cli.Context) {
-	config, err := loadConfiguration(c)
+func loadConfiguration(c *cli.Context) (*configuration.InductorConfiguration, error) {
+	layers, err := loadLayers(c)
 	if err != nil {
-		die("Couldn't load the inductor.json configuration file.", err)
-	}
-	opts, err := createRenderOpts(c, config)
-	if err != nil {
-		die(err)
-	}
-	outDir, err := outDir(c, config)
-	if err != nil {
-		die(err)
-	}
-
-	// find all templates
-	cwd, err := os.Getwd()
-	if err != nil {
-		die(err)
-	}
-	templates := tpl.New(cwd, opts.OSName)
-
-	// render all the templates to the output directory
-	renderer := renderer.New(opts, outDir)
-	err = renderer.Render(templates)
-	if err != nil {
-		die(err)
+		return nil, err
 	}
+	return layers.Configuration()
+}
 
-	// copy over any non-templates to the output directory
-	copier := cpy.New()
-	err = copier.Copy(cwd, outDir)
-	if err != nil {
-		die(err)
+// loadLayers decodes every configuration file in the order they're merged
+func loadLayers(c *cli.Context) (*configuration.Layers, error) {
+	layers := &configuration.Layers{}
+	for _, path := range configPaths(c) {
+		if err := addLayer(c, layers, path); err != nil {
+			return nil, err
+		}
 	}
+	return layers, nil
 }
 
-func loadConfiguration(c *cli.Context) (config *configuration.InductorConfiguration, err error) {
-	configFile, err := os.Open(c.String("config"))
+func addLayer(c *cli.Context, layers *configuration.Layers, path string) (err error) {
+	configFile, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer func() {
 		if cerr := configFile.Close(); cerr != nil {
 			err = cerr
 		}
 	}()
-	return configuration.New(configFile)
+	return layers.Add(path, configFile, configFormat(c, path))
+}
+
+// configFormat is the --config-format, or the format of the file extension
+func configFormat(c *cli.Context, path string) string {
+	format := c.GlobalString("config-format")
+	if len(format) == 0 {
+		format = configuration.FormatFromPath(path)
+	}
+	return format
 }
 
-func createRenderOpts(c *cli.Context, config *configuration.InductorConfiguration) (*renderer.RenderOptions, error) {
-	var osname string
-	if len(c.Args()) > 0 {
-		osname = c.Args()[0]
-	} else {
-		fmt.Println("Available Operating Systems:")
-		fmt.Println()
-		for _, s := range config.List() {
-			fmt.Println(fmt.Sprintf("  %s", s))
+// configPaths are the --config files, or inductor.json followed by the user
+// config and inductor.local.json override files when they exist, so explicit
+// --config files are never overridden by files found on disk
+func configPaths(c *cli.Context) []string {
+	if paths := c.GlobalStringSlice("config"); len(paths) > 0 {
+		return paths
+	}
+	paths := []string{"inductor.json"}
+	for _, path := range []string{userConfigPath(), "inductor.local.json"} {
+		if len(path) == 0 {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
 		}
-		fmt.Println()
-		return nil, errors.New("You must specify an operating system argument")
 	}
+	return paths
+}
 
-	// create the default options set based on the inductor config
-	var edition string
-	if len(c.String("edition")) > 0 {
-		edition = c.String("edition")
+// userConfigPath is the personal config.json in the XDG config directory,
+// which defaults to ~/.config
+func userConfigPath() string {
+	dir := xdgDir("XDG_CONFIG_HOME", ".config")
+	if len(dir) == 0 {
+		return ""
 	}
-	opts, err := renderer.NewRenderOptions(osname, edition, config)
+	return filepath.Join(dir, "inductor", "config.json")
+}
 
-	// apply any command line overrides to the options set
-	opts.WindowsUpdates = !c.Bool("skipwindowsupdates")
-	opts.Headless = !c.Bool("gui")
-	if len(c.String("productkey")) > 0 {
-		opts.ProductKey = c.String("productkey")
+// xdgDir is the XDG base directory in the environment variable, falling back
+// to the directory in the user's home directory
+func xdgDir(env, fallback string) string {
+	if dir := os.Getenv(env); len(dir) > 0 {
+		return dir
+	}
+	home := os.Getenv("HOME")
+	if len(home) == 0 {
+		home = os.Getenv("USERPROFILE")
 	}
-	if c.Bool("ssh") {
-		opts.Communicator = "ssh"
+	if len(home) == 0 {
+		return ""
 	}
+	return filepath.Join(home, fallback)
+}
 
-	return opts, err
+// osNameArg returns the required operating system argument of a command
+func osNameArg(c *cli.Context) (string, error) {
+	if len(c.Args()) == 0 {
+		return "", errors.New("You must specify an operating system argument, run 'inductor list' to see the available operating systems")
+	}
+	return c.Args()[0], nil
 }
 
-func outDir(c *cli.Context, config *configuration.InductorConfiguration) (string, error) {
-	outDir := config.OutDir
-	if len(c.String("outdir")) > 0 {
-		outDir = c.String("outdir")
+// exitError wraps the error so the CLI exits with a non-zero status code
+func exitError(err error) error {
+	if err == nil {
+		return nil
 	}
-	return filepath.Abs(outDir)
+	return cli.NewExitError(err.Error(), 1)
 }
 
 func die(vals ...interface{}) {
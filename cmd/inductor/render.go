@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/codegangsta/cli"
+	"github.com/joefitzgerald/inductor/configuration"
+	"github.com/joefitzgerald/inductor/cpy"
+	"github.com/joefitzgerald/inductor/floppy"
+	"github.com/joefitzgerald/inductor/iso"
+	"github.com/joefitzgerald/inductor/output"
+	"github.com/joefitzgerald/inductor/renderer"
+	"github.com/joefitzgerald/inductor/tpl"
+)
+
+func renderCommand() cli.Command {
+	return cli.Command{
+		Name:      "render",
+		Usage:     "Render the Packer templates for an operating system",
+		ArgsUsage: "<os> | --all",
+		Flags: append(renderOptionFlags(),
+			cli.StringFlag{
+				Name:  "format, f",
+				Value: "dir",
+				Usage: "Write to the output directory (dir) or stream a tar, tgz, zip or text of every file to stdout",
+			},
+			cli.BoolFlag{
+				Name:  "dry-run, n",
+				Usage: "Report every file that would be written without touching the output directory",
+			},
+			cli.BoolFlag{
+				Name:  "all, a",
+				Usage: "Render every OS and edition into <outdir>/<os>/<edition>",
+			},
+			cli.StringSliceFlag{
+				Name:  "include, i",
+				Usage: "Only render targets matching the os or os/edition glob, used with --all",
+			},
+			cli.StringSliceFlag{
+				Name:  "exclude, x",
+				Usage: "Skip targets matching the os or os/edition glob, used with --all",
+			},
+		),
+		Action: render,
+	}
+}
+
+// renderOptionFlags are the flags shared by all commands which render templates
+func renderOptionFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:   "outdir, o",
+			EnvVar: "INDUCTOR_OUTDIR",
+			Usage:  "The root output directory for all rendered templates",
+		},
+		cli.StringFlag{
+			Name:   "edition, e",
+			EnvVar: "INDUCTOR_EDITION",
+			Usage:  "The optional operating system edition",
+		},
+		cli.StringFlag{
+			Name:   "productkey, k",
+			EnvVar: "INDUCTOR_PRODUCTKEY",
+			Usage:  "The MS Windows product key if you have one",
+		},
+		cli.BoolFlag{
+			Name:   "skipwindowsupdates, u",
+			EnvVar: "INDUCTOR_SKIPWINDOWSUPDATES",
+			Usage:  "Skips running Windows updates on first boot",
+		},
+		cli.BoolFlag{
+			Name:   "ssh, s",
+			EnvVar: "INDUCTOR_SSH",
+			Usage:  "Uses the Packer SSH communicator instead of the default WinRM",
+		},
+		cli.BoolFlag{
+			Name:   "gui, g",
+			EnvVar: "INDUCTOR_GUI",
+			Usage:  "Run the VM with a GUI",
+		},
+		cli.BoolFlag{
+			Name:   "strict",
+			EnvVar: "INDUCTOR_STRICT",
+			Usage:  "Fail on missing template values and empty required options",
+		},
+		cli.StringSliceFlag{
+			Name:   "var-file",
+			EnvVar: "INDUCTOR_VAR_FILE",
+			Usage:  "A json, yaml or toml file of template variables, may be repeated",
+		},
+		cli.StringSliceFlag{
+			Name:   "var",
+			EnvVar: "INDUCTOR_VAR",
+			Usage:  "A key=value template variable overriding any other variables, may be repeated",
+		},
+		cli.BoolFlag{
+			Name:   "use-cache",
+			EnvVar: "INDUCTOR_USE_CACHE",
+			Usage:  "Use the ISO from the cache when it has been fetched with 'inductor fetch'",
+		},
+	}
+}
+
+func render(c *cli.Context) error {
+	stream, err := streamOutput(c.String("format"))
+	if err != nil {
+		return exitError(err)
+	}
+	if stream != nil && c.Bool("dry-run") {
+		return exitError(errors.New("The dry-run flag can only be used with the dir format"))
+	}
+	if c.Bool("all") {
+		err = renderAll(c, stream)
+	} else {
+		err = renderOne(c, stream)
+	}
+	if stream != nil {
+		if cerr := stream.Close(); cerr != nil && err == nil {
+			err = exitError(cerr)
+		}
+	}
+	return err
+}
+
+func renderOne(c *cli.Context, stream output.Archive) error {
+	if len(c.StringSlice("include")) > 0 || len(c.StringSlice("exclude")) > 0 {
+		return exitError(errors.New("The include and exclude filters can only be used with --all"))
+	}
+	osname, err := osNameArg(c)
+	if err != nil {
+		return exitError(err)
+	}
+	config, err := loadConfiguration(c)
+	if err != nil {
+		return exitError(err)
+	}
+	opts, err := createRenderOpts(c, osname, c.String("edition"), config)
+	if err != nil {
+		return exitError(err)
+	}
+	outDir, err := outDir(c, config)
+	if err != nil {
+		return exitError(err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return exitError(err)
+	}
+	return exitError(renderOutput(c, stream, cwd, outDir, "", opts))
+}
+
+// renderAll renders every selected OS and edition combination into its own
+// output directory and prints a summary of the results
+func renderAll(c *cli.Context, stream output.Archive) error {
+	if len(c.String("edition")) > 0 {
+		return exitError(errors.New("The edition flag can't be used with --all"))
+	}
+	config, err := loadConfiguration(c)
+	if err != nil {
+		return exitError(err)
+	}
+	targets, err := configuration.FilterTargets(config.Targets(), c.StringSlice("include"), c.StringSlice("exclude"))
+	if err != nil {
+		return exitError(err)
+	}
+	if len(targets) == 0 {
+		return exitError(errors.New("No targets matched the include and exclude filters"))
+	}
+	rootDir, err := outDir(c, config)
+	if err != nil {
+		return exitError(err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return exitError(err)
+	}
+
+	// keep the summary out of any output streamed to stdout
+	status := io.Writer(os.Stdout)
+	if stream != nil {
+		status = os.Stderr
+	}
+
+	failures := 0
+	for _, t := range targets {
+		err := renderTarget(c, stream, config, t, cwd, filepath.Join(rootDir, t.OSName, t.Edition))
+		if err != nil {
+			failures++
+			fmt.Fprintf(status, "FAILED  %s: %s\n", t, err)
+			continue
+		}
+		fmt.Fprintf(status, "ok      %s\n", t)
+	}
+	fmt.Fprintf(status, "\nRendered %d of %d targets\n", len(targets)-failures, len(targets))
+
+	if failures > 0 {
+		return exitError(fmt.Errorf("%d target(s) failed to render", failures))
+	}
+	return nil
+}
+
+func renderTarget(c *cli.Context, stream output.Archive, config *configuration.InductorConfiguration, t configuration.Target, srcDir, outDir string) error {
+	opts, err := createRenderOpts(c, t.OSName, t.Edition, config)
+	if err != nil {
+		return err
+	}
+	return renderOutput(c, stream, srcDir, outDir, t.String(), opts)
+}
+
+// renderOutput renders to the stream under the prefix when streaming,
+// otherwise to the output directory or with --dry-run only reports what
+// would be written to it
+func renderOutput(c *cli.Context, stream output.Archive, srcDir, outDir, prefix string, opts *renderer.RenderOptions) error {
+	if stream != nil {
+		out := output.WithPrefix(stream, prefix)
+		if _, ok := stream.(textStream); ok {
+			// the text is meant to be read, so keep the secrets out of it
+			out = output.WithRedaction(out, opts.Redact)
+		}
+		return renderTo(srcDir, outDir, out, opts)
+	}
+	if !c.Bool("dry-run") {
+		return renderTo(srcDir, outDir, output.NewDir(outDir), opts)
+	}
+	dryRun := output.NewDryRun(outDir)
+	if err := renderTo(srcDir, outDir, dryRun, opts); err != nil {
+		return err
+	}
+	printDryRun(outDir, dryRun)
+	return nil
+}
+
+// renderTo renders all templates found in srcDir and copies over any
+// non-templates to the output, skipping the output directory itself
+func renderTo(srcDir, outDir string, out output.Output, opts *renderer.RenderOptions) error {
+	// find all templates
+	templates := tpl.New(srcDir, opts.OSName)
+
+	// keep a copy of the files to pack into the floppy and CD images
+	floppyFiles := output.NewCapture(out, opts.FloppyFiles)
+	cdFiles := output.NewCapture(floppyFiles, opts.CDFiles)
+
+	// render all the templates to the output, next to the copied files
+	copier := cpy.New(cdFiles)
+	copies, err := copier.Files(srcDir, outDir)
+	if err != nil {
+		return err
+	}
+	r := renderer.NewWithCopies(opts, cdFiles, copies)
+	if err := r.Render(templates); err != nil {
+		return err
+	}
+
+	// copy over any non-templates to the output
+	if err := copier.Copy(srcDir, outDir); err != nil {
+		return err
+	}
+
+	if len(opts.FloppyImage) > 0 {
+		if err := writeFloppy(out, opts, floppyFiles.Captured()); err != nil {
+			return err
+		}
+	}
+	if len(opts.CDImage) > 0 {
+		return writeCD(out, opts, cdFiles.Captured())
+	}
+	return nil
+}
+
+// writeFloppy packs the captured files into the root of the floppy image,
+// every floppy file pattern must match at least one file
+func writeFloppy(out output.Output, opts *renderer.RenderOptions, captured *output.Memory) error {
+	if err := checkPatterns("floppy", opts.FloppyFiles, captured); err != nil {
+		return err
+	}
+	files := []floppy.File{}
+	for _, name := range captured.Names() {
+		content, _ := captured.File(name)
+		files = append(files, floppy.File{Name: path.Base(name), Content: content})
+	}
+	var img bytes.Buffer
+	if err := floppy.Write(&img, opts.OSName, files); err != nil {
+		return err
+	}
+	return out.Write(opts.FloppyImage, &img)
+}
+
+// writeCD packs the captured files into the CD image keeping their paths,
+// every CD file pattern must match at least one file
+func writeCD(out output.Output, opts *renderer.RenderOptions, captured *output.Memory) error {
+	if err := checkPatterns("CD", opts.CDFiles, captured); err != nil {
+		return err
+	}
+	files := []iso.File{}
+	for _, name := range captured.Names() {
+		content, _ := captured.File(name)
+		files = append(files, iso.File{Name: name, Content: content})
+	}
+	var img bytes.Buffer
+	if err := iso.Write(&img, opts.OSName, files); err != nil {
+		return err
+	}
+	return out.Write(opts.CDImage, &img)
+}
+
+func checkPatterns(image string, patterns []string, captured *output.Memory) error {
+	for _, pattern := range patterns {
+		if !matchesAny(pattern, captured.Names()) {
+			return fmt.Errorf("The %s file pattern '%s' doesn't match any rendered or copied file", image, pattern)
+		}
+	}
+	return nil
+}
+
+func matchesAny(pattern string, names []string) bool {
+	for _, name := range names {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// streamOutput creates the output for the format which streams to stdout,
+// the default dir format doesn't stream so has no output
+func streamOutput(format string) (output.Archive, error) {
+	switch format {
+	case "", "dir":
+		return nil, nil
+	case "tar":
+		return output.NewTar(os.Stdout), nil
+	case "tgz":
+		return output.NewTarGz(os.Stdout), nil
+	case "zip":
+		return output.NewZip(os.Stdout), nil
+	case "text":
+		return textStream{output.NewText(os.Stdout)}, nil
+	}
+	return nil, fmt.Errorf("Unknown output format '%s', expected one of dir, tar, tgz, zip or text", format)
+}
+
+// textStream is a text output which has nothing to finish on close
+type textStream struct {
+	output.Output
+}
+
+func (textStream) Close() error {
+	return nil
+}
+
+func printDryRun(outDir string, dryRun *output.DryRun) {
+	fmt.Printf("Would write to %s:\n", outDir)
+	for _, f := range dryRun.Files {
+		fmt.Printf("  %-9s %10d  %s\n", f.Status, f.Size, f.Name)
+	}
+}
+
+func createRenderOpts(c *cli.Context, osname, edition string, config *configuration.InductorConfiguration) (*renderer.RenderOptions, error) {
+	// create the default options set based on the inductor config
+	opts, err := renderer.NewRenderOptions(osname, edition, config)
+	if err != nil {
+		return nil, err
+	}
+
+	// apply any command line overrides to the options set
+	if c.Bool("skipwindowsupdates") {
+		opts.WindowsUpdates = false
+	}
+	if c.Bool("gui") {
+		opts.Headless = false
+	}
+	if len(c.String("productkey")) > 0 {
+		opts.SetProductKey(c.String("productkey"))
+	}
+	if c.Bool("ssh") {
+		opts.Communicator = "ssh"
+	}
+	if c.Bool("strict") {
+		opts.Strict = true
+	}
+	for _, path := range c.StringSlice("var-file") {
+		vars, err := loadVars(path)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetVars(vars)
+	}
+	for _, v := range c.StringSlice("var") {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 || len(kv[0]) == 0 {
+			return nil, fmt.Errorf("Invalid variable '%s', expected key=value", v)
+		}
+		opts.SetVars(map[string]interface{}{kv[0]: kv[1]})
+	}
+	if c.Bool("use-cache") {
+		cache, err := isoCache(c)
+		if err != nil {
+			return nil, err
+		}
+		if path, ok := cache.Lookup(opts.IsoChecksumType, opts.IsoChecksum); ok {
+			if err = opts.UseLocalISO(path); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// only read the secrets once they're needed to render
+	if err = opts.ResolveSecrets(); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+func loadVars(path string) (vars map[string]interface{}, err error) {
+	varsFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cerr := varsFile.Close(); cerr != nil {
+			err = cerr
+		}
+	}()
+	return configuration.NewVars(varsFile, configuration.FormatFromPath(path))
+}
+
+func outDir(c *cli.Context, config *configuration.InductorConfiguration) (string, error) {
+	outDir := config.OutDir
+	if len(c.String("outdir")) > 0 {
+		outDir = c.String("outdir")
+	}
+	return filepath.Abs(outDir)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+)
+
+func showCommand() cli.Command {
+	return cli.Command{
+		Name:      "show",
+		Usage:     "Show the configuration of an operating system",
+		ArgsUsage: "<os>",
+		Action:    show,
+	}
+}
+
+func show(c *cli.Context) error {
+	osname, err := osNameArg(c)
+	if err != nil {
+		return exitError(err)
+	}
+	config, err := loadConfiguration(c)
+	if err != nil {
+		return exitError(err)
+	}
+	osConfig, ok := config.Get(osname)
+	if !ok {
+		return exitError(fmt.Errorf("Couldn't find OS configuration for '%s'", osname))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", osConfig.Name)
+	fmt.Fprintf(w, "ISO URLs:\t%s\n", strings.Join(osConfig.AllIsoURLs(), "\n\t"))
+	fmt.Fprintf(w, "ISO checksum type:\t%s\n", osConfig.IsoChecksumType)
+	fmt.Fprintf(w, "ISO checksum:\t%s\n", osConfig.IsoChecksum)
+	fmt.Fprintf(w, "VirtualBox guest OS type:\t%s\n", osConfig.VirtualboxGuestOsType)
+	fmt.Fprintf(w, "VMware guest OS type:\t%s\n", osConfig.VmwareGuestOsType)
+	fmt.Fprintf(w, "Default edition:\t%s\n", osConfig.DefaultEdition)
+	fmt.Fprintln(w, "Editions:")
+	for _, k := range osConfig.EditionNames() {
+		fmt.Fprintf(w, "  %s\t%s\n", k, osConfig.Editions[k].WindowsImageName)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/codegangsta/cli"
+	"github.com/joefitzgerald/inductor/renderer"
+)
+
+func validateCommand() cli.Command {
+	return cli.Command{
+		Name:   "validate",
+		Usage:  "Validate the configuration of every operating system and edition",
+		Action: validate,
+	}
+}
+
+func validate(c *cli.Context) error {
+	config, err := loadConfiguration(c)
+	if err != nil {
+		return exitError(err)
+	}
+
+	failures := 0
+	for _, osname := range config.List() {
+		osConfig, _ := config.Get(osname)
+		// the edition render <os> uses without --edition
+		if _, _, err := osConfig.ResolveEdition(""); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", osname, err)
+			failures++
+			continue
+		}
+		editions := osConfig.EditionNames()
+		if len(editions) == 0 {
+			editions = append(editions, "")
+		}
+		for _, edition := range editions {
+			if _, err := renderer.NewRenderOptions(osname, edition, config); err != nil {
+				fmt.Fprintf(os.Stderr, "%s %s: %s\n", osname, edition, err)
+				failures++
+			}
+		}
+	}
+
+	if failures > 0 {
+		return exitError(fmt.Errorf("Configuration is invalid, found %d error(s)", failures))
+	}
+	fmt.Println("Configuration is valid")
+	return nil
+}
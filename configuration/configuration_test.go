@@ -34,7 +34,7 @@ var _ = Describe("Configuration", func() {
 		Expect(config.Username).To(Equal("admin"))
 	})
 	It("should a password of secret", func() {
-		Expect(config.Password).To(Equal("secret"))
+		Expect(config.Password.Value).To(Equal("secret"))
 	})
 	It("should have RAM set to 1024", func() {
 		Expect(config.RAM).To(Equal(uint32(1024)))
@@ -45,6 +45,12 @@ var _ = Describe("Configuration", func() {
 	It("should have a disk size of 10000", func() {
 		Expect(config.DiskSize).To(Equal(uint32(10000)))
 	})
+	It("should render in strict mode", func() {
+		Expect(config.Strict).To(BeTrue())
+	})
+	It("should have template vars", func() {
+		Expect(config.Vars).To(Equal(map[string]interface{}{"timezone": "UTC"}))
+	})
 	Describe("List available operating systems", func() {
 		var oses []string
 		BeforeEach(func() {
@@ -60,6 +66,43 @@ var _ = Describe("Configuration", func() {
 			Expect(oses[1]).To(Equal("windows2008r2"))
 		})
 	})
+	Describe("List render targets", func() {
+		var targets []configuration.Target
+		BeforeEach(func() {
+			targets = config.Targets()
+		})
+		It("should return every os and edition combination", func() {
+			Expect(targets).To(Equal([]configuration.Target{
+				{OSName: "windows10", Edition: "enterprise"},
+				{OSName: "windows2008r2", Edition: "enterprise"},
+				{OSName: "windows2008r2", Edition: "standard"},
+			}))
+		})
+		It("should name targets os/edition", func() {
+			Expect(targets[2].String()).To(Equal("windows2008r2/standard"))
+		})
+		It("should include everything without filters", func() {
+			filtered, err := configuration.FilterTargets(targets, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filtered).To(Equal(targets))
+		})
+		It("should match OS name patterns", func() {
+			filtered, err := configuration.FilterTargets(targets, []string{"windows2008*"}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filtered).To(HaveLen(2))
+		})
+		It("should match os/edition patterns", func() {
+			filtered, err := configuration.FilterTargets(targets, []string{"*/enterprise"}, []string{"windows10"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filtered).To(Equal([]configuration.Target{
+				{OSName: "windows2008r2", Edition: "enterprise"},
+			}))
+		})
+		It("should error on a bad pattern", func() {
+			_, err := configuration.FilterTargets(targets, []string{"["}, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
 	Describe("Get operating system configuration", func() {
 		var (
 			os      *configuration.OperatingSystem
@@ -94,6 +137,11 @@ var _ = Describe("Configuration", func() {
 			It("should have 1 edition", func() {
 				Expect(os.Editions).To(HaveLen(1))
 			})
+			It("should fall back to the first edition", func() {
+				name, _, err := os.ResolveEdition("")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(name).To(Equal("enterprise"))
+			})
 			Context("enterprise edition", func() {
 				BeforeEach(func() {
 					edition = os.Editions["enterprise"]
@@ -131,6 +179,29 @@ var _ = Describe("Configuration", func() {
 			It("should have 2 editions", func() {
 				Expect(os.Editions).To(HaveLen(2))
 			})
+			It("should list edition names in sorted order", func() {
+				Expect(os.EditionNames()).To(Equal([]string{"enterprise", "standard"}))
+			})
+			It("should resolve the default edition", func() {
+				name, edition, err := os.ResolveEdition("")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(name).To(Equal("standard"))
+				Expect(edition.WindowsImageName).To(Equal("Windows Server 2008 R2 SERVERSTANDARD"))
+			})
+			It("should resolve the requested edition", func() {
+				name, _, err := os.ResolveEdition("enterprise")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(name).To(Equal("enterprise"))
+			})
+			It("should error on an unknown edition", func() {
+				_, _, err := os.ResolveEdition("datacenter")
+				Expect(err).To(Equal(&configuration.UnknownEditionError{
+					OSName:  "windows2008r2",
+					Edition: "datacenter",
+					Valid:   []string{"enterprise", "standard"},
+				}))
+				Expect(err.Error()).To(ContainSubstring("valid editions are: enterprise, standard"))
+			})
 			Context("enterprise edition", func() {
 				BeforeEach(func() {
 					edition = os.Editions["enterprise"]
@@ -139,6 +210,13 @@ var _ = Describe("Configuration", func() {
 					Expect(edition.WindowsImageName).To(Equal("Windows Server 2008 R2 SERVERENTERPRISE"))
 				})
 			})
+			It("should override the global RAM", func() {
+				Expect(*os.RAM).To(Equal(uint32(2048)))
+			})
+			It("should not override other global settings", func() {
+				Expect(os.CPU).To(BeNil())
+				Expect(os.Headless).To(BeNil())
+			})
 			Context("standard edition", func() {
 				BeforeEach(func() {
 					edition = os.Editions["standard"]
@@ -146,6 +224,10 @@ var _ = Describe("Configuration", func() {
 				It("should have correct windows image name", func() {
 					Expect(edition.WindowsImageName).To(Equal("Windows Server 2008 R2 SERVERSTANDARD"))
 				})
+				It("should override the global CPU and communicator", func() {
+					Expect(*edition.CPU).To(Equal(uint8(2)))
+					Expect(*edition.Communicator).To(Equal("winrm"))
+				})
 			})
 		})
 		Context("linux", func() {
@@ -170,7 +252,11 @@ var testData = `
     "password":"secret",
     "ram":1024,
     "cpu":1,
-    "disk_size":10000
+    "disk_size":10000,
+    "strict":true,
+    "vars":{
+      "timezone":"UTC"
+    }
   },
   "operating_systems":{
     "windows10":{
@@ -191,9 +277,13 @@ var testData = `
       "iso_checksum":"4263be2cf3c59177c45085c0a7bc6ca5",
       "virtualbox_guest_os_type":"Windows2008_64",
       "vmware_guest_os_type":"windows7srv-64",
+      "default_edition":"standard",
+      "ram":2048,
       "editions":{
         "standard":{
-          "windows_image_name":"Windows Server 2008 R2 SERVERSTANDARD"
+          "windows_image_name":"Windows Server 2008 R2 SERVERSTANDARD",
+          "cpu":2,
+          "communicator":"winrm"
         },
         "enterprise":{
           "windows_image_name":"Windows Server 2008 R2 SERVERENTERPRISE"
@@ -0,0 +1,105 @@
+package configuration
+
+import (
+	"fmt"
+	"strings"
+)
+
+// resolveExtends replaces every OS which extends another OS with a merge of
+// the OS over its parent, so it inherits every value it doesn't set itself
+func resolveExtends(doc map[string]interface{}, v *validator) {
+	oses, ok := doc["operating_systems"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	r := &extendsResolver{
+		oses:     oses,
+		resolved: make(map[string]interface{}),
+	}
+	for _, name := range sortedKeys(oses) {
+		if _, err := r.resolve(name, nil); err != nil {
+			v.add("$.operating_systems."+name+".extends", err.Error())
+		}
+	}
+	for name, os := range r.resolved {
+		oses[name] = os
+	}
+}
+
+type extendsResolver struct {
+	oses     map[string]interface{}
+	resolved map[string]interface{}
+}
+
+func (r *extendsResolver) resolve(name string, chain []string) (interface{}, error) {
+	if os, ok := r.resolved[name]; ok {
+		return os, nil
+	}
+	os, ok := r.oses[name].(map[string]interface{})
+	parentName, _ := os["extends"].(string)
+	if !ok || len(parentName) == 0 {
+		// nothing to inherit, any invalid values are reported by validation
+		r.resolved[name] = r.oses[name]
+		return r.resolved[name], nil
+	}
+
+	chain = append(chain, name)
+	for _, n := range chain {
+		if n == parentName {
+			return nil, fmt.Errorf("extends cycle %s", strings.Join(append(chain, parentName), " -> "))
+		}
+	}
+	if _, ok := r.oses[parentName]; !ok {
+		return nil, fmt.Errorf("extends unknown operating system '%s'", parentName)
+	}
+	parent, err := r.resolve(parentName, chain)
+	if err != nil {
+		return nil, err
+	}
+	r.resolved[name] = mergeValues(parent, os)
+	return r.resolved[name], nil
+}
+
+// mergeValues deep merges src over a copy of dst, objects are merged key by
+// key and any other src value replaces the dst value
+func mergeValues(dst, src interface{}) interface{} {
+	srcObj, ok := src.(map[string]interface{})
+	if !ok {
+		return src
+	}
+	dstObj, ok := dst.(map[string]interface{})
+	if !ok {
+		return copyValue(src)
+	}
+	merged := make(map[string]interface{}, len(dstObj)+len(srcObj))
+	for k, val := range dstObj {
+		merged[k] = copyValue(val)
+	}
+	for k, val := range srcObj {
+		if existing, ok := merged[k]; ok {
+			merged[k] = mergeValues(existing, val)
+		} else {
+			merged[k] = copyValue(val)
+		}
+	}
+	return merged
+}
+
+// copyValue deep copies objects and lists so merged documents share nothing
+func copyValue(val interface{}) interface{} {
+	switch val := val.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, v := range val {
+			m[k] = copyValue(v)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(val))
+		for i, v := range val {
+			l[i] = copyValue(v)
+		}
+		return l
+	}
+	return val
+}
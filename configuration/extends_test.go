@@ -0,0 +1,113 @@
+package configuration_test
+
+import (
+	"strings"
+
+	"github.com/joefitzgerald/inductor/configuration"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Operating system inheritance", func() {
+	var (
+		err    error
+		config *configuration.InductorConfiguration
+		src    string
+	)
+
+	JustBeforeEach(func() {
+		config, err = configuration.New(strings.NewReader(src))
+	})
+
+	Context("with valid parents", func() {
+		BeforeEach(func() {
+			src = extendsTestData
+		})
+		It("should not error", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("should inherit values it doesn't set", func() {
+			os, _ := config.Get("windows2012r2core")
+			Expect(os.IsoURL).To(Equal("http://example.com/windows2012r2.iso"))
+			Expect(os.IsoChecksum).To(Equal("849734f37346385dac2c101e4aacba4626bb141c"))
+			Expect(os.VirtualboxGuestOsType).To(Equal("Windows2012_64"))
+		})
+		It("should keep its own values", func() {
+			os, _ := config.Get("windows2012r2core")
+			Expect(os.Name).To(Equal("windows2012r2core"))
+			Expect(os.Extends).To(Equal("windows2012r2"))
+			Expect(os.DefaultEdition).To(Equal("standardcore"))
+		})
+		It("should merge the editions", func() {
+			os, _ := config.Get("windows2012r2core")
+			Expect(os.EditionNames()).To(Equal([]string{"standard", "standardcore"}))
+		})
+		It("should inherit through several levels", func() {
+			os, _ := config.Get("windows2012r2corehyperv")
+			Expect(os.IsoURL).To(Equal("http://example.com/windows2012r2.iso"))
+			Expect(os.DefaultEdition).To(Equal("standardcore"))
+			Expect(*os.RAM).To(Equal(uint32(4096)))
+		})
+		It("should not change the parent", func() {
+			os, _ := config.Get("windows2012r2")
+			Expect(os.EditionNames()).To(Equal([]string{"standard"}))
+			Expect(os.RAM).To(BeNil())
+		})
+	})
+
+	Context("with an unknown parent and a cycle", func() {
+		BeforeEach(func() {
+			src = `{
+  "config":{},
+  "operating_systems":{
+    "a":{"extends":"b", "iso_url":"http://example.com/a.iso"},
+    "b":{"extends":"a", "iso_url":"http://example.com/b.iso"},
+    "c":{"extends":"windows95", "iso_url":"http://example.com/c.iso"}
+  }
+}`
+		})
+		It("should report every problem", func() {
+			validationErr, ok := err.(*configuration.ValidationError)
+			Expect(ok).To(BeTrue())
+			Expect(validationErr.Errors).To(Equal([]configuration.FieldError{
+				{Path: "$.operating_systems.a.extends", Message: "extends cycle a -> b -> a"},
+				{Path: "$.operating_systems.b.extends", Message: "extends cycle b -> a -> b"},
+				{Path: "$.operating_systems.c.extends", Message: "extends unknown operating system 'windows95'"},
+			}))
+		})
+	})
+})
+
+var extendsTestData = `
+{
+  "config":{},
+  "operating_systems":{
+    "windows2012r2":{
+      "iso_url":"http://example.com/windows2012r2.iso",
+      "iso_checksum_type":"sha1",
+      "iso_checksum":"849734f37346385dac2c101e4aacba4626bb141c",
+      "virtualbox_guest_os_type":"Windows2012_64",
+      "vmware_guest_os_type":"windows8srv-64",
+      "editions":{
+        "standard":{
+          "windows_image_name":"Windows Server 2012 R2 SERVERSTANDARD"
+        }
+      }
+    },
+    "windows2012r2core":{
+      "extends":"windows2012r2",
+      "default_edition":"standardcore",
+      "editions":{
+        "standardcore":{
+          "windows_image_name":"Windows Server 2012 R2 SERVERSTANDARDCORE"
+        }
+      }
+    },
+    "windows2012r2corehyperv":{
+      "extends":"windows2012r2core",
+      "ram":4096
+    }
+  }
+}
+`
@@ -0,0 +1,113 @@
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Supported configuration file formats
+const (
+	JSON = "json"
+	YAML = "yaml"
+	TOML = "toml"
+)
+
+// FormatFromPath returns the configuration format for the file extension,
+// defaulting to JSON
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return YAML
+	case ".toml":
+		return TOML
+	}
+	return JSON
+}
+
+// NewFromFormat creates an initialized InductorConfiguration from a source
+// in the given format. YAML and TOML sources use the same keys as JSON.
+func NewFromFormat(configSrc io.Reader, format string) (*InductorConfiguration, error) {
+	doc, err := decodeDocument(configSrc, format)
+	if err != nil {
+		return nil, err
+	}
+	return newFromDocument(doc)
+}
+
+// NewVars decodes a file of template variables in the given format
+func NewVars(varsSrc io.Reader, format string) (map[string]interface{}, error) {
+	return decodeDocument(varsSrc, format)
+}
+
+// decodeDocument decodes the source into a map of keys, converting YAML
+// and TOML values into the same types the JSON decoder uses
+func decodeDocument(configSrc io.Reader, format string) (map[string]interface{}, error) {
+	if format == JSON {
+		var doc map[string]interface{}
+		dec := json.NewDecoder(configSrc)
+		if err := dec.Decode(&doc); err != nil {
+			return nil, err
+		}
+		return doc, nil
+	}
+
+	src, err := ioutil.ReadAll(configSrc)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	switch format {
+	case YAML:
+		err = yaml.Unmarshal(src, &doc)
+	case TOML:
+		_, err = toml.Decode(string(src), &doc)
+	default:
+		return nil, fmt.Errorf("Unknown configuration format '%s', expected one of json, yaml or toml", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := stringKeys(doc).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Expected the %s source to be a map of keys", format)
+	}
+	return m, nil
+}
+
+// stringKeys converts the map[interface{}]interface{} maps decoded from YAML
+// into map[string]interface{} which can be encoded as JSON
+func stringKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = stringKeys(val)
+		}
+		return m
+	case map[string]interface{}:
+		for k, val := range v {
+			v[k] = stringKeys(val)
+		}
+		return v
+	case []interface{}:
+		for i, val := range v {
+			v[i] = stringKeys(val)
+		}
+		return v
+	case []map[string]interface{}:
+		s := make([]interface{}, len(v))
+		for i, val := range v {
+			s[i] = stringKeys(val)
+		}
+		return s
+	}
+	return v
+}
@@ -0,0 +1,143 @@
+package configuration_test
+
+import (
+	"strings"
+
+	"github.com/joefitzgerald/inductor/configuration"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Configuration formats", func() {
+	var (
+		err    error
+		config *configuration.InductorConfiguration
+	)
+
+	Describe("Format from path", func() {
+		It("should detect YAML", func() {
+			Expect(configuration.FormatFromPath("inductor.yml")).To(Equal(configuration.YAML))
+			Expect(configuration.FormatFromPath("inductor.YAML")).To(Equal(configuration.YAML))
+		})
+		It("should detect TOML", func() {
+			Expect(configuration.FormatFromPath("/etc/inductor.toml")).To(Equal(configuration.TOML))
+		})
+		It("should default to JSON", func() {
+			Expect(configuration.FormatFromPath("inductor.json")).To(Equal(configuration.JSON))
+			Expect(configuration.FormatFromPath("inductor")).To(Equal(configuration.JSON))
+		})
+	})
+
+	assertDecoded := func() {
+		It("should not error", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("should decode the global config", func() {
+			Expect(config.Communicator).To(Equal("ssh"))
+			Expect(config.RAM).To(Equal(uint32(4096)))
+			Expect(config.Headless).To(BeFalse())
+		})
+		It("should keep defaults for missing keys", func() {
+			Expect(config.WindowsUpdates).To(BeTrue())
+		})
+		It("should decode the operating systems", func() {
+			os, found := config.Get("windows10")
+			Expect(found).To(BeTrue())
+			Expect(os.Name).To(Equal("windows10"))
+			Expect(os.IsoChecksumType).To(Equal("sha1"))
+			Expect(os.Editions["enterprise"].WindowsImageName).To(Equal("Windows 10 Enterprise Evaluation"))
+		})
+	}
+
+	Describe("YAML", func() {
+		BeforeEach(func() {
+			config, err = configuration.NewFromFormat(strings.NewReader(yamlTestData), configuration.YAML)
+		})
+		assertDecoded()
+
+		Context("with an unquoted numeric checksum and product key", func() {
+			BeforeEach(func() {
+				src := strings.Replace(yamlTestData, "56ab095075be28a90bc0b510835280975c6bb2ce", "1234567890", 1)
+				src += "        product_key: 12345\n"
+				config, err = configuration.NewFromFormat(strings.NewReader(src), configuration.YAML)
+			})
+			It("should ask for the values to be quoted", func() {
+				Expect(err).To(MatchError(ContainSubstring("$.operating_systems.windows10.iso_checksum: expected a string, got a number, quote the value so it's read as a string")))
+				Expect(err).To(MatchError(ContainSubstring("$.operating_systems.windows10.editions.enterprise.product_key: expected a string or a secret source object, got a number, quote the value so it's read as a string")))
+			})
+		})
+		Context("with a quoted numeric checksum", func() {
+			BeforeEach(func() {
+				src := strings.Replace(yamlTestData, "56ab095075be28a90bc0b510835280975c6bb2ce", "'0123456789'", 1)
+				config, err = configuration.NewFromFormat(strings.NewReader(src), configuration.YAML)
+			})
+			It("should keep the string", func() {
+				Expect(err).NotTo(HaveOccurred())
+				os, _ := config.Get("windows10")
+				Expect(os.IsoChecksum).To(Equal("0123456789"))
+			})
+		})
+	})
+
+	Describe("TOML", func() {
+		BeforeEach(func() {
+			config, err = configuration.NewFromFormat(strings.NewReader(tomlTestData), configuration.TOML)
+		})
+		assertDecoded()
+	})
+
+	Describe("Vars", func() {
+		It("should decode a YAML vars file", func() {
+			vars, err := configuration.NewVars(strings.NewReader("timezone: UTC\nproxy_port: 3128\n"), configuration.YAML)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vars).To(Equal(map[string]interface{}{"timezone": "UTC", "proxy_port": 3128}))
+		})
+		It("should decode a JSON vars file", func() {
+			vars, err := configuration.NewVars(strings.NewReader(`{"org":"ACME"}`), configuration.JSON)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vars).To(Equal(map[string]interface{}{"org": "ACME"}))
+		})
+	})
+
+	Describe("Unknown format", func() {
+		BeforeEach(func() {
+			config, err = configuration.NewFromFormat(strings.NewReader(""), "ini")
+		})
+		It("should error", func() {
+			Expect(err).To(MatchError(ContainSubstring("Unknown configuration format 'ini'")))
+		})
+	})
+})
+
+var yamlTestData = `
+# global settings shared by every OS
+config:
+  headless: false
+  communicator: ssh
+  ram: 4096
+operating_systems:
+  windows10:
+    iso_url: http://example.com/windows10.iso
+    iso_checksum_type: sha1
+    iso_checksum: 56ab095075be28a90bc0b510835280975c6bb2ce
+    editions:
+      enterprise:
+        windows_image_name: Windows 10 Enterprise Evaluation
+`
+
+var tomlTestData = `
+# global settings shared by every OS
+[config]
+headless = false
+communicator = "ssh"
+ram = 4096
+
+[operating_systems.windows10]
+iso_url = "http://example.com/windows10.iso"
+iso_checksum_type = "sha1"
+iso_checksum = "56ab095075be28a90bc0b510835280975c6bb2ce"
+
+[operating_systems.windows10.editions.enterprise]
+windows_image_name = "Windows 10 Enterprise Evaluation"
+`
@@ -2,39 +2,82 @@ package configuration
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"sort"
+	"strings"
 )
 
 // InductorConfiguration contains all OS details
 type InductorConfiguration struct {
-	Headless         bool   `json:"headless"`
-	WindowsUpdates   bool   `json:"windows_updates"`
-	Communicator     string `json:"communicator"`
-	OutDir           string `json:"out_dir"`
-	Username         string `json:"username"`
-	Password         string `json:"password"`
-	DiskSize         uint32 `json:"disk_size"`
-	RAM              uint32 `json:"ram"`
-	CPU              uint8  `json:"cpu"`
+	Headless         bool                   `json:"headless"`
+	WindowsUpdates   bool                   `json:"windows_updates"`
+	Communicator     string                 `json:"communicator"`
+	OutDir           string                 `json:"out_dir"`
+	Username         string                 `json:"username"`
+	Password         Secret                 `json:"password"`
+	DiskSize         uint32                 `json:"disk_size"`
+	RAM              uint32                 `json:"ram"`
+	CPU              uint8                  `json:"cpu"`
+	Strict           bool                   `json:"strict"`
+	FloppyFiles      []string               `json:"floppy_files"`
+	CDFiles          []string               `json:"cd_files"`
+	Vars             map[string]interface{} `json:"vars"`
 	OperatingSystems map[string]OperatingSystem
 }
 
 // OperatingSystem has all the OS specific details required for Packer
 type OperatingSystem struct {
 	Name                  string
+	Extends               string             `json:"extends"`
 	IsoChecksum           string             `json:"iso_checksum"`
 	IsoChecksumType       string             `json:"iso_checksum_type"`
 	IsoURL                string             `json:"iso_url"`
+	IsoURLs               []string           `json:"iso_urls"`
 	VirtualboxGuestOsType string             `json:"virtualbox_guest_os_type"`
 	VmwareGuestOsType     string             `json:"vmware_guest_os_type"`
+	DefaultEdition        string             `json:"default_edition"`
 	Editions              map[string]Edition `json:"editions"`
+	Settings
 }
 
 // Edition is the Windows edition, e.g. Enterprise, Home
 type Edition struct {
 	WindowsImageName string `json:"windows_image_name"`
-	ProductKey       string `json:"product_key"`
+	ProductKey       Secret `json:"product_key"`
+	Settings
+}
+
+// Settings are the global build settings an OS or edition can override,
+// nil settings aren't overridden. Vars are merged over the global vars.
+// FloppyFiles and CDFiles are the path.Match patterns of the rendered and
+// copied files to pack into a floppy image and a CD image.
+type Settings struct {
+	Headless       *bool                  `json:"headless"`
+	WindowsUpdates *bool                  `json:"windows_updates"`
+	Communicator   *string                `json:"communicator"`
+	Username       *string                `json:"username"`
+	Password       *Secret                `json:"password"`
+	DiskSize       *uint32                `json:"disk_size"`
+	RAM            *uint32                `json:"ram"`
+	CPU            *uint8                 `json:"cpu"`
+	FloppyFiles    []string               `json:"floppy_files"`
+	CDFiles        []string               `json:"cd_files"`
+	Vars           map[string]interface{} `json:"vars"`
+}
+
+// UnknownEditionError is returned when an edition isn't configured for an OS
+type UnknownEditionError struct {
+	OSName  string
+	Edition string
+	Valid   []string
+}
+
+func (e *UnknownEditionError) Error() string {
+	if len(e.Valid) == 0 {
+		return fmt.Sprintf("Couldn't find edition '%s' for OS '%s', it has no editions", e.Edition, e.OSName)
+	}
+	return fmt.Sprintf("Couldn't find edition '%s' for OS '%s', valid editions are: %s", e.Edition, e.OSName, strings.Join(e.Valid, ", "))
 }
 
 // List all available OS names
@@ -58,25 +101,107 @@ func (reg *InductorConfiguration) Get(osName string) (*OperatingSystem, bool) {
 	return nil, ok
 }
 
-// New creates an initialized InductorConfiguration
+// AllIsoURLs lists every ISO URL in the order Packer should try them, the
+// iso_url followed by any iso_urls
+func (os *OperatingSystem) AllIsoURLs() []string {
+	urls := []string{}
+	if len(os.IsoURL) > 0 {
+		urls = append(urls, os.IsoURL)
+	}
+	for _, url := range os.IsoURLs {
+		if url != os.IsoURL {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+// EditionNames lists all edition names of the OS in sorted order
+func (os *OperatingSystem) EditionNames() []string {
+	keys := make([]string, 0, len(os.Editions))
+	for k := range os.Editions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ResolveEdition returns the named edition. When no name is given the OS
+// default edition is used, falling back to the first edition in sorted order.
+func (os *OperatingSystem) ResolveEdition(name string) (string, Edition, error) {
+	if len(name) == 0 {
+		name = os.DefaultEdition
+	}
+	if len(name) == 0 {
+		names := os.EditionNames()
+		if len(names) == 0 {
+			return "", Edition{}, nil
+		}
+		name = names[0]
+	}
+	edition, ok := os.Editions[name]
+	if !ok {
+		return "", Edition{}, &UnknownEditionError{
+			OSName:  os.Name,
+			Edition: name,
+			Valid:   os.EditionNames(),
+		}
+	}
+	return name, edition, nil
+}
+
+// defaultConfig are the global settings used when the config doesn't set them
+func defaultConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"headless":        true,
+		"windows_updates": true,
+		"communicator":    "winrm",
+		"out_dir":         "out",
+	}
+}
+
+// prepareDocument interpolates environment variables, resolves extends and
+// validates the document, then fills in the default global settings
+func prepareDocument(doc map[string]interface{}) error {
+	v := &validator{}
+	interpolateEnv(doc, v)
+	resolveExtends(doc, v)
+	v.validateDocument(doc)
+	if err := v.err(); err != nil {
+		return err
+	}
+	doc["config"] = mergeValues(defaultConfig(), doc["config"])
+	return nil
+}
+
+// New creates an initialized InductorConfiguration from a JSON source
 func New(configSrc io.Reader) (*InductorConfiguration, error) {
+	return NewFromFormat(configSrc, JSON)
+}
+
+// newFromDocument interpolates, resolves, validates and decodes a configuration
+// document, which may have come from any of the supported formats
+func newFromDocument(doc map[string]interface{}) (*InductorConfiguration, error) {
+	if err := prepareDocument(doc); err != nil {
+		return nil, err
+	}
+	src, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
 	configuration := InductorConfiguration{
-		Headless:         true,
-		WindowsUpdates:   true,
-		Communicator:     "winrm",
-		OutDir:           "out",
 		OperatingSystems: make(map[string]OperatingSystem),
 	}
 
 	// decode outermost defaults and operations_systems into a map
 	var topObjMap map[string]*json.RawMessage
-	dec := json.NewDecoder(configSrc)
-	if err := dec.Decode(&topObjMap); err != nil {
+	if err = json.Unmarshal(src, &topObjMap); err != nil {
 		return nil, err
 	}
 
 	// decode global config into main config
-	err := json.Unmarshal(*topObjMap["config"], &configuration)
+	err = json.Unmarshal(*topObjMap["config"], &configuration)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,55 @@
+package configuration
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// envPattern matches $${ escapes, ${NAME} and ${NAME:-default} references
+var envPattern = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// interpolateEnv replaces environment variable references in every string
+// value of the document, reporting any variable which isn't set
+func interpolateEnv(doc map[string]interface{}, v *validator) {
+	for _, k := range sortedKeys(doc) {
+		doc[k] = interpolateValue("$."+k, doc[k], v)
+	}
+}
+
+func interpolateValue(path string, value interface{}, v *validator) interface{} {
+	switch value := value.(type) {
+	case string:
+		return interpolateString(path, value, v)
+	case map[string]interface{}:
+		for _, k := range sortedKeys(value) {
+			value[k] = interpolateValue(path+"."+k, value[k], v)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = interpolateValue(fmt.Sprintf("%s[%d]", path, i), item, v)
+		}
+	}
+	return value
+}
+
+// interpolateString expands ${NAME} to the value of the environment variable
+// and ${NAME:-default} to the default when the variable is unset or empty.
+// $${ is a literal ${, any other $ is left as it is.
+func interpolateString(path, s string, v *validator) string {
+	return envPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		if ref == "$${" {
+			return "${"
+		}
+		m := envPattern.FindStringSubmatch(ref)
+		name, hasDefault, def := m[1], len(m[2]) > 0, m[3]
+		val, ok := os.LookupEnv(name)
+		if hasDefault && len(val) == 0 {
+			return def
+		}
+		if !ok {
+			v.add(path, "environment variable '%s' is not set", name)
+		}
+		return val
+	})
+}
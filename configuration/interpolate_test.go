@@ -0,0 +1,84 @@
+package configuration_test
+
+import (
+	"os"
+	"strings"
+
+	"github.com/joefitzgerald/inductor/configuration"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Environment variable interpolation", func() {
+	var (
+		err    error
+		config *configuration.InductorConfiguration
+		src    string
+	)
+
+	BeforeEach(func() {
+		os.Setenv("INDUCTOR_TEST_PASSWORD", "s3cret")
+		os.Setenv("INDUCTOR_TEST_MIRROR", "iso.example.com")
+		os.Setenv("INDUCTOR_TEST_EMPTY", "")
+		os.Unsetenv("INDUCTOR_TEST_UNSET")
+		src = `{
+  "config":{
+    "username":"${INDUCTOR_TEST_UNSET:-vagrant}",
+    "password":"${INDUCTOR_TEST_PASSWORD}",
+    "communicator":"${INDUCTOR_TEST_EMPTY:-winrm}",
+    "vars":{
+      "price":"$$5",
+      "template":"$${NAME}",
+      "reference":"$$${INDUCTOR_TEST_MIRROR}"
+    }
+  },
+  "operating_systems":{
+    "windows10":{
+      "iso_url":"http://${INDUCTOR_TEST_MIRROR}/windows10.iso"
+    }
+  }
+}`
+	})
+	AfterEach(func() {
+		os.Unsetenv("INDUCTOR_TEST_PASSWORD")
+		os.Unsetenv("INDUCTOR_TEST_MIRROR")
+		os.Unsetenv("INDUCTOR_TEST_EMPTY")
+	})
+	JustBeforeEach(func() {
+		config, err = configuration.New(strings.NewReader(src))
+	})
+
+	It("should not error", func() {
+		Expect(err).NotTo(HaveOccurred())
+	})
+	It("should replace set variables", func() {
+		Expect(config.Password.Value).To(Equal("s3cret"))
+		os, _ := config.Get("windows10")
+		Expect(os.IsoURL).To(Equal("http://iso.example.com/windows10.iso"))
+	})
+	It("should use the default for unset or empty variables", func() {
+		Expect(config.Username).To(Equal("vagrant"))
+		Expect(config.Communicator).To(Equal("winrm"))
+	})
+	It("should keep a $$ outside an expression", func() {
+		Expect(config.Vars["price"]).To(Equal("$$5"))
+	})
+	It("should unescape $${", func() {
+		Expect(config.Vars["template"]).To(Equal("${NAME}"))
+		Expect(config.Vars["reference"]).To(Equal("$${INDUCTOR_TEST_MIRROR}"))
+	})
+
+	Context("with an unset variable", func() {
+		BeforeEach(func() {
+			src = strings.Replace(src, "${INDUCTOR_TEST_PASSWORD}", "${INDUCTOR_TEST_UNSET}", 1)
+		})
+		It("should report the variable and path", func() {
+			validationErr, ok := err.(*configuration.ValidationError)
+			Expect(ok).To(BeTrue())
+			Expect(validationErr.Errors).To(Equal([]configuration.FieldError{
+				{Path: "$.config.password", Message: "environment variable 'INDUCTOR_TEST_UNSET' is not set"},
+			}))
+		})
+	})
+})
@@ -0,0 +1,142 @@
+package configuration
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Layers are configuration documents from several sources, e.g. a shared team
+// configuration and a personal override file. They're deep merged in order so
+// the values of later layers override those of earlier layers.
+type Layers struct {
+	names []string
+	docs  []map[string]interface{}
+}
+
+// Value is a value of the merged configuration and the layer it came from
+type Value struct {
+	Path   string
+	Value  interface{}
+	Source string
+}
+
+// Add decodes the named source in the given format as the next layer
+func (l *Layers) Add(name string, src io.Reader, format string) error {
+	doc, err := decodeDocument(src, format)
+	if err != nil {
+		return fmt.Errorf("Couldn't decode %s: %s", name, err)
+	}
+	l.names = append(l.names, name)
+	l.docs = append(l.docs, doc)
+	return nil
+}
+
+// Configuration merges the layers into one InductorConfiguration. Environment
+// variables and extends are resolved after merging, so a layer can extend an
+// OS from another layer.
+func (l *Layers) Configuration() (*InductorConfiguration, error) {
+	return newFromDocument(l.merge())
+}
+
+// DefaultSource is the source of values none of the layers set
+const DefaultSource = "default"
+
+// Values lists every value of the effective configuration in path order with
+// the name of the last layer which set it. Like Configuration, environment
+// variables are interpolated, extends are resolved and defaults are filled
+// in. An inherited value's source is the layer which set it on the extended
+// OS, and a default's source is DefaultSource. Lists are replaced as a whole
+// by later layers so are a single value. Plain password and product key
+// values are redacted.
+func (l *Layers) Values() ([]Value, error) {
+	sources := make(map[string]string)
+	for i, doc := range l.docs {
+		walkValues("$", doc, func(p string, _ interface{}) {
+			sources[p] = l.names[i]
+		})
+	}
+	merged := l.merge()
+	extends := make(map[string]string)
+	oses, _ := merged["operating_systems"].(map[string]interface{})
+	for name, os := range oses {
+		obj, _ := os.(map[string]interface{})
+		extends[name], _ = obj["extends"].(string)
+	}
+	if err := prepareDocument(merged); err != nil {
+		return nil, err
+	}
+
+	var values []Value
+	walkValues("$", merged, func(p string, val interface{}) {
+		if s, ok := val.(string); ok && len(s) > 0 && isSecretPath(p) {
+			val = Redacted
+		}
+		values = append(values, Value{Path: p, Value: val, Source: valueSource(p, sources, extends)})
+	})
+	return values, nil
+}
+
+// valueSource finds the layer which set the value, following the extends
+// chain of an OS to the OS it inherited the value from
+func valueSource(p string, sources, extends map[string]string) string {
+	const osPrefix = "$.operating_systems."
+	for seen := make(map[string]bool); ; {
+		if source, ok := sources[p]; ok {
+			return source
+		}
+		if !strings.HasPrefix(p, osPrefix) {
+			return DefaultSource
+		}
+		parts := strings.SplitN(strings.TrimPrefix(p, osPrefix), ".", 2)
+		parent := extends[parts[0]]
+		if len(parts) < 2 || len(parent) == 0 || seen[parent] {
+			return DefaultSource
+		}
+		seen[parent] = true
+		p = osPrefix + parent + "." + parts[1]
+	}
+}
+
+// Source returns the name of the last layer which sets the value at the key
+// path, e.g. operating_systems, windows10, iso_checksum
+func (l *Layers) Source(keys ...string) (string, bool) {
+	for i := len(l.docs) - 1; i >= 0; i-- {
+		var val interface{} = l.docs[i]
+		for _, k := range keys {
+			obj, _ := val.(map[string]interface{})
+			if val = obj[k]; val == nil {
+				break
+			}
+		}
+		if val != nil {
+			return l.names[i], true
+		}
+	}
+	return "", false
+}
+
+func (l *Layers) merge() map[string]interface{} {
+	var merged interface{} = map[string]interface{}{}
+	for _, doc := range l.docs {
+		merged = mergeValues(merged, doc)
+	}
+	return merged.(map[string]interface{})
+}
+
+// walkValues calls fn with the path of every value which isn't a non-empty
+// object, in sorted key order
+func walkValues(p string, val interface{}, fn func(string, interface{})) {
+	obj, ok := val.(map[string]interface{})
+	if !ok || len(obj) == 0 {
+		fn(p, val)
+		return
+	}
+	for _, k := range sortedKeys(obj) {
+		walkValues(p+"."+k, obj[k], fn)
+	}
+}
+
+func isSecretPath(p string) bool {
+	return strings.HasSuffix(p, ".password") || strings.HasSuffix(p, ".product_key")
+}
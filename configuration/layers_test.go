@@ -0,0 +1,135 @@
+package configuration_test
+
+import (
+	"os"
+	"strings"
+
+	"github.com/joefitzgerald/inductor/configuration"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Layered configuration", func() {
+	var (
+		err    error
+		layers *configuration.Layers
+		config *configuration.InductorConfiguration
+	)
+
+	BeforeEach(func() {
+		layers = &configuration.Layers{}
+		Expect(layers.Add("inductor.json", strings.NewReader(`{
+  "config":{"username":"vagrant","password":"vagrant","ram":4096,"cpu":2},
+  "operating_systems":{
+    "windows10":{
+      "iso_url":"http://example.com/windows10.iso",
+      "iso_checksum_type":"sha1",
+      "editions":{"enterprise":{"windows_image_name":"Windows 10 Enterprise"}}
+    }
+  }
+}`), configuration.JSON)).To(Succeed())
+		Expect(layers.Add("inductor.local.yml", strings.NewReader(`
+config:
+  ram: 1024
+operating_systems:
+  windows10:
+    iso_url: file:///nas/windows10.iso
+  windows10-pro:
+    extends: windows10
+    editions:
+      pro:
+        windows_image_name: Windows 10 Pro
+`), configuration.YAML)).To(Succeed())
+	})
+
+	Describe("Configuration", func() {
+		JustBeforeEach(func() {
+			config, err = layers.Configuration()
+		})
+		It("should not error", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("should override values with later layers", func() {
+			Expect(config.RAM).To(Equal(uint32(1024)))
+			os, _ := config.Get("windows10")
+			Expect(os.IsoURL).To(Equal("file:///nas/windows10.iso"))
+		})
+		It("should keep values later layers don't set", func() {
+			Expect(config.CPU).To(Equal(uint8(2)))
+			os, _ := config.Get("windows10")
+			Expect(os.IsoChecksumType).To(Equal("sha1"))
+			Expect(os.EditionNames()).To(Equal([]string{"enterprise"}))
+		})
+		It("should extend an OS from another layer", func() {
+			os, found := config.Get("windows10-pro")
+			Expect(found).To(BeTrue())
+			Expect(os.IsoURL).To(Equal("file:///nas/windows10.iso"))
+			Expect(os.EditionNames()).To(Equal([]string{"enterprise", "pro"}))
+		})
+	})
+
+	Describe("Values", func() {
+		BeforeEach(func() {
+			os.Setenv("INDUCTOR_TEST_CHECKSUM", "56ab095075be28a90bc0b510835280975c6bb2ce")
+			Expect(layers.Add("inductor.env.json", strings.NewReader(`{
+  "operating_systems":{"windows10":{"iso_checksum":"${INDUCTOR_TEST_CHECKSUM}"}}
+}`), configuration.JSON)).To(Succeed())
+		})
+		AfterEach(func() {
+			os.Unsetenv("INDUCTOR_TEST_CHECKSUM")
+		})
+		It("should list every effective value with the layer it came from", func() {
+			values, err := layers.Values()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(values).To(Equal([]configuration.Value{
+				{Path: "$.config.communicator", Value: "winrm", Source: "default"},
+				{Path: "$.config.cpu", Value: float64(2), Source: "inductor.json"},
+				{Path: "$.config.headless", Value: true, Source: "default"},
+				{Path: "$.config.out_dir", Value: "out", Source: "default"},
+				{Path: "$.config.password", Value: "********", Source: "inductor.json"},
+				{Path: "$.config.ram", Value: 1024, Source: "inductor.local.yml"},
+				{Path: "$.config.username", Value: "vagrant", Source: "inductor.json"},
+				{Path: "$.config.windows_updates", Value: true, Source: "default"},
+				{Path: "$.operating_systems.windows10.editions.enterprise.windows_image_name", Value: "Windows 10 Enterprise", Source: "inductor.json"},
+				{Path: "$.operating_systems.windows10.iso_checksum", Value: "56ab095075be28a90bc0b510835280975c6bb2ce", Source: "inductor.env.json"},
+				{Path: "$.operating_systems.windows10.iso_checksum_type", Value: "sha1", Source: "inductor.json"},
+				{Path: "$.operating_systems.windows10.iso_url", Value: "file:///nas/windows10.iso", Source: "inductor.local.yml"},
+				{Path: "$.operating_systems.windows10-pro.editions.enterprise.windows_image_name", Value: "Windows 10 Enterprise", Source: "inductor.json"},
+				{Path: "$.operating_systems.windows10-pro.editions.pro.windows_image_name", Value: "Windows 10 Pro", Source: "inductor.local.yml"},
+				{Path: "$.operating_systems.windows10-pro.extends", Value: "windows10", Source: "inductor.local.yml"},
+				{Path: "$.operating_systems.windows10-pro.iso_checksum", Value: "56ab095075be28a90bc0b510835280975c6bb2ce", Source: "inductor.env.json"},
+				{Path: "$.operating_systems.windows10-pro.iso_checksum_type", Value: "sha1", Source: "inductor.json"},
+				{Path: "$.operating_systems.windows10-pro.iso_url", Value: "file:///nas/windows10.iso", Source: "inductor.local.yml"},
+			}))
+		})
+		It("should return configuration errors", func() {
+			os.Unsetenv("INDUCTOR_TEST_CHECKSUM")
+			_, err := layers.Values()
+			Expect(err).To(MatchError(ContainSubstring("environment variable 'INDUCTOR_TEST_CHECKSUM' is not set")))
+		})
+	})
+
+	Describe("Source", func() {
+		It("should return the last layer which sets the value", func() {
+			source, _ := layers.Source("operating_systems", "windows10", "iso_url")
+			Expect(source).To(Equal("inductor.local.yml"))
+			source, _ = layers.Source("operating_systems", "windows10", "iso_checksum_type")
+			Expect(source).To(Equal("inductor.json"))
+			source, ok := layers.Source("operating_systems", "windows10")
+			Expect(ok).To(BeTrue())
+			Expect(source).To(Equal("inductor.local.yml"))
+		})
+		It("should return false for unset values", func() {
+			_, ok := layers.Source("operating_systems", "windows10", "iso_checksum")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Context("with an undecodable layer", func() {
+		It("should name the layer", func() {
+			err = layers.Add("broken.json", strings.NewReader(`{`), configuration.JSON)
+			Expect(err).To(MatchError(HavePrefix("Couldn't decode broken.json: ")))
+		})
+	})
+})
@@ -0,0 +1,128 @@
+package configuration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Redacted replaces secret values in any output
+const Redacted = "********"
+
+// Secret is a sensitive value like a password or product key. It's either
+// given directly as a string or as an object referencing a file, environment
+// variable or command whose output is the value, e.g. {"env": "VM_PASSWORD"}.
+// Sources are only read when the secret is resolved at render time.
+type Secret struct {
+	Value   string `json:"-"`
+	File    string `json:"file"`
+	Env     string `json:"env"`
+	Command string `json:"command"`
+}
+
+// UnmarshalJSON decodes a plain string value or a secret source object
+func (s *Secret) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &s.Value); err == nil {
+		return nil
+	}
+	type source Secret
+	return json.Unmarshal(b, (*source)(s))
+}
+
+// IsSource returns true when the value comes from a file, environment
+// variable or command instead of the configuration
+func (s Secret) IsSource() bool {
+	return len(s.File) > 0 || len(s.Env) > 0 || len(s.Command) > 0
+}
+
+// Resolve reads the secret value from its source. A single trailing newline
+// is removed from file and command output.
+func (s Secret) Resolve() (string, error) {
+	switch {
+	case len(s.File) > 0:
+		content, err := ioutil.ReadFile(s.File)
+		if err != nil {
+			return "", fmt.Errorf("Couldn't read secret file: %s", err)
+		}
+		return trimNewline(string(content)), nil
+	case len(s.Env) > 0:
+		value, ok := os.LookupEnv(s.Env)
+		if !ok {
+			return "", fmt.Errorf("Secret environment variable '%s' is not set", s.Env)
+		}
+		return value, nil
+	case len(s.Command) > 0:
+		var stdout, stderr bytes.Buffer
+		cmd := shellCommand(s.Command)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("Secret command '%s' failed: %s %s", s.Command, err, strings.TrimSpace(stderr.String()))
+		}
+		return trimNewline(stdout.String()), nil
+	}
+	return s.Value, nil
+}
+
+// String describes the secret without revealing its value
+func (s Secret) String() string {
+	switch {
+	case len(s.File) > 0:
+		return "file:" + s.File
+	case len(s.Env) > 0:
+		return "env:" + s.Env
+	case len(s.Command) > 0:
+		return "command:" + s.Command
+	case len(s.Value) > 0:
+		return Redacted
+	}
+	return ""
+}
+
+// validateSecret checks the value is a string or an object with exactly one
+// secret source
+func (v *validator) validateSecret(path string, value interface{}) bool {
+	if _, ok := value.(string); ok {
+		return true
+	}
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		v.add(path, "expected a string or a secret source object, got %s%s", typeName(value), quoteHint(value))
+		return false
+	}
+	valid := true
+	for _, k := range sortedKeys(obj) {
+		if k != "file" && k != "env" && k != "command" {
+			v.add(path+"."+k, "unknown field")
+			valid = false
+		} else if s, ok := obj[k].(string); !ok {
+			v.add(path+"."+k, "expected a string, got %s", typeName(obj[k]))
+			valid = false
+		} else if len(s) == 0 {
+			v.add(path+"."+k, "must not be empty")
+			valid = false
+		}
+	}
+	if valid && len(obj) != 1 {
+		v.add(path, "expected exactly one of file, env or command")
+		return false
+	}
+	return valid
+}
+
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
+
+func trimNewline(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	return strings.TrimSuffix(s, "\r")
+}
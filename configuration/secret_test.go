@@ -0,0 +1,125 @@
+package configuration_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joefitzgerald/inductor/configuration"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Secret", func() {
+	Describe("decoding", func() {
+		var (
+			err    error
+			config *configuration.InductorConfiguration
+			src    string
+		)
+
+		JustBeforeEach(func() {
+			config, err = configuration.New(strings.NewReader(src))
+		})
+
+		Context("with secret sources", func() {
+			BeforeEach(func() {
+				src = `{
+  "config":{"password":{"env":"VM_PASSWORD"}},
+  "operating_systems":{
+    "windows10":{
+      "iso_url":"http://example.com/windows10.iso",
+      "password":{"command":"pass show vagrant"},
+      "editions":{
+        "enterprise":{
+          "windows_image_name":"Windows 10 Enterprise",
+          "product_key":{"file":"windows10.key"}
+        },
+        "pro":{
+          "windows_image_name":"Windows 10 Pro",
+          "product_key":"KEY"
+        }
+      }
+    }
+  }
+}`
+			})
+			It("should decode the sources without resolving them", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.Password).To(Equal(configuration.Secret{Env: "VM_PASSWORD"}))
+				os, _ := config.Get("windows10")
+				Expect(*os.Password).To(Equal(configuration.Secret{Command: "pass show vagrant"}))
+				Expect(os.Editions["enterprise"].ProductKey).To(Equal(configuration.Secret{File: "windows10.key"}))
+				Expect(os.Editions["pro"].ProductKey).To(Equal(configuration.Secret{Value: "KEY"}))
+			})
+		})
+
+		Context("with invalid secret sources", func() {
+			BeforeEach(func() {
+				src = `{
+  "config":{"password":{"env":"VM_PASSWORD","file":"password.txt"}},
+  "operating_systems":{
+    "windows10":{
+      "iso_url":"http://example.com/windows10.iso",
+      "password":{"vault":"secret/vagrant"},
+      "editions":{
+        "enterprise":{
+          "windows_image_name":"Windows 10 Enterprise",
+          "product_key":42
+        }
+      }
+    }
+  }
+}`
+			})
+			It("should report every problem", func() {
+				validationErr, ok := err.(*configuration.ValidationError)
+				Expect(ok).To(BeTrue())
+				Expect(validationErr.Errors).To(Equal([]configuration.FieldError{
+					{Path: "$.config.password", Message: "expected exactly one of file, env or command"},
+					{Path: "$.operating_systems.windows10.editions.enterprise.product_key", Message: "expected a string or a secret source object, got a number, quote the value so it's read as a string"},
+					{Path: "$.operating_systems.windows10.password.vault", Message: "unknown field"},
+				}))
+			})
+		})
+	})
+
+	Describe("Resolve", func() {
+		It("should return a plain value", func() {
+			Expect(configuration.Secret{Value: "vagrant"}.Resolve()).To(Equal("vagrant"))
+		})
+		It("should read a file without the trailing newline", func() {
+			dir, err := ioutil.TempDir("", "inductor")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "password.txt")
+			Expect(ioutil.WriteFile(path, []byte("s3cret\n"), 0600)).To(Succeed())
+			Expect(configuration.Secret{File: path}.Resolve()).To(Equal("s3cret"))
+		})
+		It("should read an environment variable", func() {
+			os.Setenv("INDUCTOR_TEST_SECRET", "s3cret")
+			defer os.Unsetenv("INDUCTOR_TEST_SECRET")
+			Expect(configuration.Secret{Env: "INDUCTOR_TEST_SECRET"}.Resolve()).To(Equal("s3cret"))
+		})
+		It("should error when the environment variable isn't set", func() {
+			_, err := configuration.Secret{Env: "INDUCTOR_TEST_UNSET"}.Resolve()
+			Expect(err).To(MatchError("Secret environment variable 'INDUCTOR_TEST_UNSET' is not set"))
+		})
+		It("should read the output of a command", func() {
+			Expect(configuration.Secret{Command: "echo s3cret"}.Resolve()).To(Equal("s3cret"))
+		})
+		It("should error when the command fails", func() {
+			_, err := configuration.Secret{Command: "exit 3"}.Resolve()
+			Expect(err).To(MatchError(HavePrefix("Secret command 'exit 3' failed")))
+		})
+	})
+
+	Describe("String", func() {
+		It("should not reveal the value", func() {
+			Expect(configuration.Secret{Value: "s3cret"}.String()).To(Equal("********"))
+			Expect(configuration.Secret{Env: "VM_PASSWORD"}.String()).To(Equal("env:VM_PASSWORD"))
+		})
+	})
+})
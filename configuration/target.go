@@ -0,0 +1,81 @@
+package configuration
+
+import (
+	"path"
+	"strings"
+)
+
+// Target is a single operating system and edition combination to render
+type Target struct {
+	OSName  string
+	Edition string
+}
+
+// String returns the target name in the form os/edition
+func (t Target) String() string {
+	if len(t.Edition) == 0 {
+		return t.OSName
+	}
+	return t.OSName + "/" + t.Edition
+}
+
+// Match reports whether the target matches the glob pattern. Patterns
+// containing a slash are matched against os/edition, all others against
+// just the OS name.
+func (t Target) Match(pattern string) (bool, error) {
+	if strings.Contains(pattern, "/") {
+		return path.Match(pattern, t.OSName+"/"+t.Edition)
+	}
+	return path.Match(pattern, t.OSName)
+}
+
+// Targets lists every OS and edition combination in sorted order. An OS
+// without any editions is listed once with an empty edition.
+func (reg *InductorConfiguration) Targets() []Target {
+	targets := []Target{}
+	for _, osName := range reg.List() {
+		os := reg.OperatingSystems[osName]
+		editions := os.EditionNames()
+		if len(editions) == 0 {
+			targets = append(targets, Target{OSName: osName})
+			continue
+		}
+		for _, edition := range editions {
+			targets = append(targets, Target{OSName: osName, Edition: edition})
+		}
+	}
+	return targets
+}
+
+// FilterTargets returns the targets matching any of the include patterns
+// and none of the exclude patterns. No include patterns includes everything.
+func FilterTargets(targets []Target, includes, excludes []string) ([]Target, error) {
+	filtered := []Target{}
+	for _, t := range targets {
+		included := len(includes) == 0
+		for _, pattern := range includes {
+			ok, err := t.Match(pattern)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				included = true
+				break
+			}
+		}
+		for _, pattern := range excludes {
+			ok, err := t.Match(pattern)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				included = false
+				break
+			}
+		}
+		if included {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered, nil
+}
@@ -0,0 +1,97 @@
+package configuration
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// sentinel temporarily stands in for a value to find where it's set, it's a
+// plain word which doesn't need quoting in any format
+const sentinel = "inductorsetstringsentinel"
+
+// SetString replaces the existing string at the key path of a configuration
+// source in the given format, e.g. operating_systems, windows10, iso_checksum,
+// and returns the updated source. Only the value is replaced, keeping the
+// formatting, key order and comments of the file. An error asking for the
+// file to be updated by hand is returned when the value isn't set, references
+// an environment variable or can't be found in the source.
+func SetString(src []byte, format string, keys []string, value string) ([]byte, error) {
+	doc, err := decodeDocument(bytes.NewReader(src), format)
+	if err != nil {
+		return nil, err
+	}
+	path := strings.Join(keys, ".")
+	existing, ok := lookupString(doc, keys)
+	if !ok || len(existing) == 0 {
+		return nil, fmt.Errorf("%s isn't set, add it with the value '%s' by hand", path, value)
+	}
+	if envPattern.MatchString(existing) {
+		return nil, fmt.Errorf("%s references an environment variable, update it to '%s' by hand", path, value)
+	}
+
+	// replace the one occurrence of the value which decodes as the value at
+	// the key path, ignoring the same value set elsewhere
+	var found []byte
+	for offset := 0; ; {
+		i := bytes.Index(src[offset:], []byte(existing))
+		if i < 0 {
+			break
+		}
+		i += offset
+		offset = i + 1
+		candidate := replaceAt(src, i, existing, sentinel)
+		if !setsOnly(candidate, format, doc, keys, sentinel) {
+			continue
+		}
+		if found != nil {
+			found = nil
+			break
+		}
+		found = replaceAt(src, i, existing, value)
+	}
+	if found == nil || !setsOnly(found, format, doc, keys, value) {
+		return nil, fmt.Errorf("Couldn't find where %s is set in the file, update it to '%s' by hand", path, value)
+	}
+	return found, nil
+}
+
+func replaceAt(src []byte, i int, old, new string) []byte {
+	updated := make([]byte, 0, len(src)-len(old)+len(new))
+	updated = append(updated, src[:i]...)
+	updated = append(updated, new...)
+	return append(updated, src[i+len(old):]...)
+}
+
+// setsOnly returns true when the updated source decodes to the original
+// document with only the value at the key path changed
+func setsOnly(updated []byte, format string, doc map[string]interface{}, keys []string, value string) bool {
+	updatedDoc, err := decodeDocument(bytes.NewReader(updated), format)
+	if err != nil {
+		return false
+	}
+	if s, ok := lookupString(updatedDoc, keys); !ok || s != value {
+		return false
+	}
+	existing, _ := lookupString(doc, keys)
+	obj := updatedDoc
+	for _, k := range keys[:len(keys)-1] {
+		obj = obj[k].(map[string]interface{})
+	}
+	obj[keys[len(keys)-1]] = existing
+	return reflect.DeepEqual(updatedDoc, doc)
+}
+
+func lookupString(doc map[string]interface{}, keys []string) (string, bool) {
+	var val interface{} = doc
+	for _, k := range keys {
+		obj, ok := val.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		val = obj[k]
+	}
+	s, ok := val.(string)
+	return s, ok
+}
@@ -0,0 +1,76 @@
+package configuration_test
+
+import (
+	"github.com/joefitzgerald/inductor/configuration"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SetString", func() {
+	keys := []string{"operating_systems", "windows10", "iso_checksum"}
+
+	It("should replace an existing value in place", func() {
+		src := "# team registry\noperating_systems:\n  windows10:\n    iso_checksum: abc123   # from MSDN\n"
+		updated, err := configuration.SetString([]byte(src), configuration.YAML, keys, "def456")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(updated)).To(Equal("# team registry\noperating_systems:\n  windows10:\n    iso_checksum: def456   # from MSDN\n"))
+	})
+	It("should only replace the value at the key path when the value is duplicated", func() {
+		src := `{
+  "operating_systems": {
+    "windows10n": {"iso_checksum": "abc", "iso_url": "abc.iso"},
+    "windows10": {"iso_checksum": "abc"}
+  }
+}`
+		updated, err := configuration.SetString([]byte(src), configuration.JSON, keys, "def456")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(updated)).To(Equal(`{
+  "operating_systems": {
+    "windows10n": {"iso_checksum": "abc", "iso_url": "abc.iso"},
+    "windows10": {"iso_checksum": "def456"}
+  }
+}`))
+	})
+	It("should keep the comments of a YAML file with a duplicated value", func() {
+		src := `# shared checksums
+operating_systems:
+  windows10n:
+    iso_checksum: abc123 # the N edition
+  windows10:
+    # the retail ISO
+    iso_checksum: abc123
+`
+		updated, err := configuration.SetString([]byte(src), configuration.YAML, keys, "def456")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(updated)).To(Equal(`# shared checksums
+operating_systems:
+  windows10n:
+    iso_checksum: abc123 # the N edition
+  windows10:
+    # the retail ISO
+    iso_checksum: def456
+`))
+	})
+	It("should replace a TOML value in place", func() {
+		src := "# checksums\n[operating_systems.windows10]\niso_checksum = \"abc123\"\n"
+		updated, err := configuration.SetString([]byte(src), configuration.TOML, keys, "def456")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(updated)).To(Equal("# checksums\n[operating_systems.windows10]\niso_checksum = \"def456\"\n"))
+	})
+	It("should not overwrite an interpolated value", func() {
+		src := `{"operating_systems":{"windows10":{"iso_checksum":"${WINDOWS10_CHECKSUM}"}}}`
+		_, err := configuration.SetString([]byte(src), configuration.JSON, keys, "def456")
+		Expect(err).To(MatchError("operating_systems.windows10.iso_checksum references an environment variable, update it to 'def456' by hand"))
+	})
+	It("should not add a missing value", func() {
+		src := `{"operating_systems":{"windows10":{"iso_checksum_type":"sha1"}}}`
+		_, err := configuration.SetString([]byte(src), configuration.JSON, keys, "def456")
+		Expect(err).To(MatchError("operating_systems.windows10.iso_checksum isn't set, add it with the value 'def456' by hand"))
+	})
+	It("should not write a value which decodes differently", func() {
+		src := "operating_systems:\n  windows10:\n    iso_checksum: abc123\n"
+		_, err := configuration.SetString([]byte(src), configuration.YAML, keys, "123456")
+		Expect(err).To(MatchError("Couldn't find where operating_systems.windows10.iso_checksum is set in the file, update it to '123456' by hand"))
+	})
+})
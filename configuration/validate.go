@@ -0,0 +1,274 @@
+package configuration
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// secretType is validated as a string or a secret source
+var secretType = reflect.TypeOf(Secret{})
+
+// checksumTypes are the ISO checksum types supported by Packer
+var checksumTypes = []string{"md5", "sha1", "sha256", "sha512"}
+
+// FieldError is a problem with the value at a JSON path in the configuration
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidationError contains every problem found in a configuration
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	lines := []string{fmt.Sprintf("Invalid configuration, found %d error(s):", len(e.Errors))}
+	for _, fe := range e.Errors {
+		lines = append(lines, "  "+fe.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+type validator struct {
+	errors []FieldError
+}
+
+func (v *validator) add(path, format string, args ...interface{}) {
+	v.errors = append(v.errors, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns all the problems found as a ValidationError, or nil if none
+func (v *validator) err() error {
+	if len(v.errors) > 0 {
+		return &ValidationError{Errors: v.errors}
+	}
+	return nil
+}
+
+// validateDocument checks the configuration document for missing sections,
+// unknown fields, wrong types and invalid values
+func (v *validator) validateDocument(doc map[string]interface{}) {
+	for _, k := range sortedKeys(doc) {
+		if k != "config" && k != "operating_systems" {
+			v.add("$."+k, "unknown field")
+		}
+	}
+
+	if config, ok := doc["config"]; ok {
+		v.validateValue("$.config", config, reflect.TypeOf(InductorConfiguration{}))
+	} else {
+		v.add("$.config", "missing section")
+	}
+
+	if oses, ok := doc["operating_systems"]; ok {
+		osType := reflect.TypeOf(map[string]OperatingSystem{})
+		if v.validateValue("$.operating_systems", oses, osType) {
+			v.validateOperatingSystems(oses.(map[string]interface{}))
+		}
+	} else {
+		v.add("$.operating_systems", "missing section")
+	}
+}
+
+// validateOperatingSystems checks the values every OS needs to render
+func (v *validator) validateOperatingSystems(oses map[string]interface{}) {
+	for _, name := range sortedKeys(oses) {
+		path := "$.operating_systems." + name
+		os, ok := oses[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		urls, _ := os["iso_urls"].([]interface{})
+		for i, u := range urls {
+			if url, ok := u.(string); ok && len(url) == 0 {
+				v.add(fmt.Sprintf("%s.iso_urls[%d]", path, i), "must not be empty")
+			}
+		}
+		if url, _ := os["iso_url"].(string); len(url) == 0 && len(urls) == 0 {
+			v.add(path+".iso_url", "must not be empty")
+		}
+		if checksumType, ok := os["iso_checksum_type"].(string); ok && !isChecksumType(checksumType) {
+			v.add(path+".iso_checksum_type", "invalid checksum type '%s', expected one of %s", checksumType, strings.Join(checksumTypes, ", "))
+		}
+		editions, _ := os["editions"].(map[string]interface{})
+		for _, edition := range sortedKeys(editions) {
+			e, ok := editions[edition].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if imageName, _ := e["windows_image_name"].(string); len(imageName) == 0 {
+				v.add(path+".editions."+edition+".windows_image_name", "must not be empty")
+			}
+		}
+		if name, _ := os["default_edition"].(string); len(name) > 0 {
+			if _, ok := editions[name]; !ok {
+				if len(editions) == 0 {
+					v.add(path+".default_edition", "unknown edition '%s', the OS has no editions", name)
+				} else {
+					v.add(path+".default_edition", "unknown edition '%s', expected one of %s", name, strings.Join(sortedKeys(editions), ", "))
+				}
+			}
+		}
+	}
+}
+
+// validateValue checks the decoded value can be decoded into the Go type,
+// returning false when it can't
+func (v *validator) validateValue(path string, value interface{}, t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		if value == nil {
+			return true
+		}
+		t = t.Elem()
+	}
+	if t == secretType {
+		return v.validateSecret(path, value)
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			v.add(path, "expected an object, got %s", typeName(value))
+			return false
+		}
+		fields := jsonFields(t)
+		for _, k := range sortedKeys(obj) {
+			field, ok := fields[k]
+			if !ok {
+				v.add(path+"."+k, "unknown field")
+				continue
+			}
+			v.validateValue(path+"."+k, obj[k], field.Type)
+		}
+	case reflect.Map:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			v.add(path, "expected an object, got %s", typeName(value))
+			return false
+		}
+		for _, k := range sortedKeys(obj) {
+			v.validateValue(path+"."+k, obj[k], t.Elem())
+		}
+	case reflect.Slice:
+		list, ok := value.([]interface{})
+		if !ok {
+			v.add(path, "expected a list, got %s", typeName(value))
+			return false
+		}
+		for i, item := range list {
+			v.validateValue(fmt.Sprintf("%s[%d]", path, i), item, t.Elem())
+		}
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			v.add(path, "expected a string, got %s%s", typeName(value), quoteHint(value))
+			return false
+		}
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			v.add(path, "expected a boolean, got %s", typeName(value))
+			return false
+		}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := toNumber(value)
+		if !ok {
+			v.add(path, "expected a number, got %s", typeName(value))
+			return false
+		}
+		max := math.Pow(2, float64(t.Bits())) - 1
+		if n != math.Trunc(n) || n < 0 || n > max {
+			v.add(path, "expected a whole number between 0 and %.0f, got %v", max, value)
+			return false
+		}
+	}
+	return true
+}
+
+// jsonFields maps the JSON name of every configurable field to the field,
+// including those of embedded structs. Fields without a json tag can't be
+// configured.
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			for name, ef := range jsonFields(f.Type) {
+				fields[name] = ef
+			}
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if len(name) > 0 && name != "-" {
+			fields[name] = f
+		}
+	}
+	return fields
+}
+
+func toNumber(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "a list"
+	}
+	if _, ok := toNumber(value); ok {
+		return "a number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// quoteHint suggests quoting a number where a string is expected, e.g. an
+// unquoted YAML checksum made up of digits is decoded as a number
+func quoteHint(value interface{}) string {
+	if _, ok := toNumber(value); ok {
+		return ", quote the value so it's read as a string"
+	}
+	return ""
+}
+
+func isChecksumType(checksumType string) bool {
+	for _, t := range checksumTypes {
+		if t == checksumType {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
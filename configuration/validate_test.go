@@ -0,0 +1,149 @@
+package configuration_test
+
+import (
+	"strings"
+
+	"github.com/joefitzgerald/inductor/configuration"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Configuration validation", func() {
+	var (
+		err    error
+		config *configuration.InductorConfiguration
+		src    string
+	)
+
+	JustBeforeEach(func() {
+		config, err = configuration.New(strings.NewReader(src))
+	})
+
+	Context("with missing sections", func() {
+		BeforeEach(func() {
+			src = `{"operating_system":{}}`
+		})
+		It("should not panic and report every problem", func() {
+			Expect(config).To(BeNil())
+			Expect(err).To(Equal(&configuration.ValidationError{Errors: []configuration.FieldError{
+				{Path: "$.operating_system", Message: "unknown field"},
+				{Path: "$.config", Message: "missing section"},
+				{Path: "$.operating_systems", Message: "missing section"},
+			}}))
+		})
+	})
+
+	Context("with invalid fields", func() {
+		BeforeEach(func() {
+			src = invalidTestData
+		})
+		It("should report every problem with its JSON path", func() {
+			validationErr, ok := err.(*configuration.ValidationError)
+			Expect(ok).To(BeTrue())
+			Expect(validationErr.Errors).To(Equal([]configuration.FieldError{
+				{Path: "$.config.cpu", Message: "expected a whole number between 0 and 255, got 512"},
+				{Path: "$.config.headles", Message: "unknown field"},
+				{Path: "$.config.ram", Message: "expected a number, got a string"},
+				{Path: "$.operating_systems.windows10.editions.enterprise.windows_image_nam", Message: "unknown field"},
+				{Path: "$.operating_systems.windows2008r2.iso_checksum", Message: "expected a string, got a number, quote the value so it's read as a string"},
+				{Path: "$.operating_systems.windows10.iso_url", Message: "must not be empty"},
+				{Path: "$.operating_systems.windows10.iso_checksum_type", Message: "invalid checksum type 'sha3', expected one of md5, sha1, sha256, sha512"},
+				{Path: "$.operating_systems.windows10.editions.enterprise.windows_image_name", Message: "must not be empty"},
+			}))
+		})
+		It("should list the problems in the error message", func() {
+			Expect(err.Error()).To(HavePrefix("Invalid configuration, found 8 error(s):\n  $.config.cpu: "))
+		})
+	})
+
+	Context("with ISO URL lists", func() {
+		BeforeEach(func() {
+			src = `{
+  "config":{},
+  "operating_systems":{
+    "windows10":{"iso_urls":["file:///nas/windows10.iso","http://mirror/windows10.iso"]},
+    "windows81":{"iso_urls":["http://mirror/windows81.iso",""]},
+    "windows7":{"iso_urls":[]}
+  }
+}`
+		})
+		It("should accept a list instead of iso_url", func() {
+			config, err := configuration.New(strings.NewReader(`{"config":{},"operating_systems":{"windows10":{"iso_urls":["http://mirror/windows10.iso"]}}}`))
+			Expect(err).NotTo(HaveOccurred())
+			os, _ := config.Get("windows10")
+			Expect(os.AllIsoURLs()).To(Equal([]string{"http://mirror/windows10.iso"}))
+		})
+		It("should report empty URLs", func() {
+			validationErr, ok := err.(*configuration.ValidationError)
+			Expect(ok).To(BeTrue())
+			Expect(validationErr.Errors).To(Equal([]configuration.FieldError{
+				{Path: "$.operating_systems.windows7.iso_url", Message: "must not be empty"},
+				{Path: "$.operating_systems.windows81.iso_urls[1]", Message: "must not be empty"},
+			}))
+		})
+	})
+
+	Context("with an unknown default edition", func() {
+		BeforeEach(func() {
+			src = `{
+  "config":{},
+  "operating_systems":{
+    "windows10":{
+      "iso_url":"http://mirror/windows10.iso",
+      "default_edition":"bogus",
+      "editions":{
+        "enterprise":{"windows_image_name":"Windows 10 Enterprise"},
+        "pro":{"windows_image_name":"Windows 10 Pro"}
+      }
+    },
+    "windows7":{"iso_url":"http://mirror/windows7.iso","default_edition":"ultimate"}
+  }
+}`
+		})
+		It("should report the default edition", func() {
+			validationErr, ok := err.(*configuration.ValidationError)
+			Expect(ok).To(BeTrue())
+			Expect(validationErr.Errors).To(Equal([]configuration.FieldError{
+				{Path: "$.operating_systems.windows10.default_edition", Message: "unknown edition 'bogus', expected one of enterprise, pro"},
+				{Path: "$.operating_systems.windows7.default_edition", Message: "unknown edition 'ultimate', the OS has no editions"},
+			}))
+		})
+	})
+
+	Context("with a valid configuration", func() {
+		BeforeEach(func() {
+			src = testData
+		})
+		It("should not error", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config).NotTo(BeNil())
+		})
+	})
+})
+
+var invalidTestData = `
+{
+  "config":{
+    "headles":false,
+    "ram":"1024",
+    "cpu":512
+  },
+  "operating_systems":{
+    "windows10":{
+      "iso_url":"",
+      "iso_checksum_type":"sha3",
+      "editions":{
+        "enterprise":{
+          "windows_image_nam":"Windows 10 Enterprise Evaluation"
+        }
+      }
+    },
+    "windows2008r2":{
+      "iso_url":"http://example.com/windows2008r2.iso",
+      "iso_checksum_type":"md5",
+      "iso_checksum":4263
+    }
+  }
+}
+`
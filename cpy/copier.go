@@ -1,7 +1,9 @@
 package cpy
 
-// Copier will recursively copy all the file from the source directory
-// to the given output directory
+// Copier will recursively copy all the files from the source directory
+// to its output, never copying the skip directory
 type Copier interface {
-	Copy(srcDir, outDir string) error
+	Copy(srcDir, skipDir string) error
+	// Files lists the slash separated paths Copy would write to its output
+	Files(srcDir, skipDir string) ([]string, error)
 }
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/joefitzgerald/inductor/cpy"
+	"github.com/joefitzgerald/inductor/output"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -33,7 +34,7 @@ var _ = Describe("Cpy", func() {
 		createFile(srcDir, ".git/blah")
 		createFile(srcDir, ".gitignore")
 		outDir = filepath.Join(srcDir, "out")
-		copier = cpy.New()
+		copier = cpy.New(output.NewDir(outDir))
 		err = copier.Copy(srcDir, outDir)
 	})
 	AfterEach(func() {
@@ -74,6 +75,33 @@ var _ = Describe("Cpy", func() {
 			Expect(path).ToNot(BeADirectory())
 		})
 	})
+
+	Describe("Files", func() {
+		It("should list the files it copies", func() {
+			files, err := copier.Files(srcDir, outDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(ConsistOf("README.md", "Vagrantfile", "scripts/winrm.ps1",
+				"scripts/windows-updates.ps1", "scripts/nano/SetupComplete.cmd"))
+		})
+	})
+
+	Describe("Copy into nested out dir", func() {
+		var nestedOutDir string
+		BeforeEach(func() {
+			nestedOutDir = filepath.Join(outDir, "windows10", "enterprise")
+			err = cpy.New(output.NewDir(nestedOutDir)).Copy(srcDir, nestedOutDir)
+		})
+		It("should not error", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("should copy regular files in src dir", func() {
+			Expect(filepath.Join(nestedOutDir, "README.md")).To(BeARegularFile())
+		})
+		It("should not copy the dir containing the out dir", func() {
+			path := filepath.Join(nestedOutDir, "out")
+			Expect(path).ToNot(BeADirectory())
+		})
+	})
 })
 
 func createFile(baseDir, path string) {
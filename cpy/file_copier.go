@@ -2,30 +2,49 @@ package cpy
 
 import (
 	"errors"
-	"io"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/joefitzgerald/inductor/output"
 )
 
 type fileCopier struct {
-	srcDir string
-	outDir string
+	out     output.Output
+	srcDir  string
+	skipDir string
+	visit   func(source, target string) error
 }
 
-// New create a new cpy instance
-func New() Copier {
-	return &fileCopier{}
+// New create a new cpy instance which copies to the given output
+func New(out output.Output) Copier {
+	return &fileCopier{out: out}
 }
 
-func (cp *fileCopier) Copy(srcDir, outDir string) error {
-	if err := cp.initCopyDirs(srcDir, outDir); err != nil {
+func (cp *fileCopier) Copy(srcDir, skipDir string) error {
+	if err := cp.initCopyDirs(srcDir, skipDir); err != nil {
 		return err
 	}
+	cp.visit = cp.copyFile
 	return filepath.Walk(srcDir, cp.walkFile)
 }
 
-func (cp *fileCopier) initCopyDirs(srcDir, outDir string) error {
+func (cp *fileCopier) Files(srcDir, skipDir string) ([]string, error) {
+	if err := cp.initCopyDirs(srcDir, skipDir); err != nil {
+		return nil, err
+	}
+	files := []string{}
+	cp.visit = func(source, target string) error {
+		files = append(files, target)
+		return nil
+	}
+	if err := filepath.Walk(srcDir, cp.walkFile); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (cp *fileCopier) initCopyDirs(srcDir, skipDir string) error {
 	sfi, err := os.Stat(srcDir)
 	if err != nil {
 		return err
@@ -33,31 +52,19 @@ func (cp *fileCopier) initCopyDirs(srcDir, outDir string) error {
 	if !sfi.IsDir() {
 		return errors.New("Expected Copy source to be a directory")
 	}
-
-	tfi, err := os.Stat(outDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			err = mkdir(outDir)
-			if err != nil {
-				return err
-			}
-		} else {
-			return err
-		}
-	} else {
-		if !tfi.IsDir() {
-			return errors.New("Expected Copy destination to be a directory")
-		}
-	}
 	cp.srcDir = srcDir
-	cp.outDir = outDir
+	cp.skipDir = skipDir
 	return nil
 }
 
 func (cp *fileCopier) walkFile(sf string, sfi os.FileInfo, err error) error {
+	if err != nil {
+		return err
+	}
 	if sfi.IsDir() {
-		// don't copy hidden dirs or the output dir into the output dir
-		if isHiddenFileOrDir(sfi) || sf == cp.outDir {
+		// don't copy hidden dirs or the skip dir (or any dir containing it,
+		// e.g. a batch render root) into the output
+		if isHiddenFileOrDir(sfi) || cp.containsSkipDir(sf) {
 			return filepath.SkipDir
 		}
 		return nil
@@ -69,21 +76,27 @@ func (cp *fileCopier) walkFile(sf string, sfi os.FileInfo, err error) error {
 	}
 
 	// we have a file, calculate its relative destination location
-	rel := strings.TrimPrefix(sf, cp.srcDir)
-	df := filepath.Join(cp.outDir, rel)
-	dir := filepath.Dir(df)
-
-	if err = mkdir(dir); err != nil {
+	rel, err := filepath.Rel(cp.srcDir, sf)
+	if err != nil {
 		return err
 	}
-	if err = cp.copyFile(sf, df); err != nil {
-		return err
+	return cp.visit(sf, filepath.ToSlash(rel))
+}
+
+func (cp *fileCopier) containsSkipDir(dir string) bool {
+	if len(cp.skipDir) == 0 {
+		return false
 	}
-	return nil
+	if dir == cp.skipDir {
+		return true
+	}
+	if dir == cp.srcDir {
+		return false
+	}
+	return strings.HasPrefix(cp.skipDir, dir+string(filepath.Separator))
 }
 
 func (cp *fileCopier) copyFile(source, target string) (err error) {
-	//fmt.Println(fmt.Sprintf("%s => %s", source, target))
 	sf, err := os.Open(source)
 	if err != nil {
 		return err
@@ -93,21 +106,7 @@ func (cp *fileCopier) copyFile(source, target string) (err error) {
 			err = cerr
 		}
 	}()
-	tf, err := os.Create(target)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if cerr := tf.Close(); cerr != nil {
-			err = cerr
-		}
-	}()
-	_, err = io.Copy(tf, sf)
-	if err != nil {
-		return err
-	}
-	err = tf.Sync()
-	return err
+	return cp.out.Write(target, sf)
 }
 
 func isHiddenFileOrDir(fi os.FileInfo) bool {
@@ -117,7 +116,3 @@ func isHiddenFileOrDir(fi os.FileInfo) bool {
 func isTemplate(file string) bool {
 	return filepath.Ext(file) == ".template" || filepath.Ext(file) == ".partial"
 }
-
-func mkdir(dir string) error {
-	return os.MkdirAll(dir, 0777)
-}
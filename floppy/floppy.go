@@ -0,0 +1,269 @@
+// Package floppy writes 1.44MB FAT12 floppy disk images
+package floppy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// The geometry of a 1.44MB 3.5" floppy disk
+const (
+	sectorSize        = 512
+	totalSectors      = 2880
+	reservedSectors   = 1
+	fatCount          = 2
+	sectorsPerFAT     = 9
+	rootEntries       = 224
+	sectorsPerTrack   = 18
+	heads             = 2
+	mediaDescriptor   = 0xf0
+	dirEntrySize      = 32
+	rootSectors       = rootEntries * dirEntrySize / sectorSize
+	dataStart         = reservedSectors + fatCount*sectorsPerFAT + rootSectors
+	clusterCount      = totalSectors - dataStart
+	firstCluster      = 2
+	endOfChain        = 0xfff
+	attrArchive       = 0x20
+	attrLongName      = 0x0f
+	longNameChars     = 13
+	lastLongNameEntry = 0x40
+)
+
+// Capacity is the number of bytes a floppy image can hold
+const Capacity = clusterCount * sectorSize
+
+// DefaultName is the file name of the rendered floppy image
+const DefaultName = "floppy.vfd"
+
+// fatDate is 1980-01-01, the FAT epoch, so images are reproducible
+const fatDate = 1<<5 | 1
+
+// File is a file to put in the root directory of the floppy
+type File struct {
+	Name    string
+	Content []byte
+}
+
+// Write writes a 1.44MB FAT12 floppy image containing the files in its root
+// directory to w. Long file names are stored as VFAT long names so Windows
+// setup finds e.g. Autounattend.xml. An error is returned when the files
+// don't fit.
+func Write(w io.Writer, label string, files []File) error {
+	clustersNeeded := 0
+	entriesNeeded := 0
+	names := make(map[string]bool)
+	for _, f := range files {
+		if names[strings.ToLower(f.Name)] {
+			return fmt.Errorf("More than one floppy file is named %s", f.Name)
+		}
+		names[strings.ToLower(f.Name)] = true
+		clustersNeeded += (len(f.Content) + sectorSize - 1) / sectorSize
+		entriesNeeded += 1 + longNameEntries(f.Name)
+	}
+	if clustersNeeded > clusterCount {
+		return fmt.Errorf("The floppy files need %d KB but a 1.44MB floppy only holds %d KB", clustersNeeded*sectorSize/1024, Capacity/1024)
+	}
+	if entriesNeeded > rootEntries {
+		return fmt.Errorf("The floppy files need %d directory entries but a floppy's root directory only holds %d", entriesNeeded, rootEntries)
+	}
+
+	img := make([]byte, totalSectors*sectorSize)
+	writeBootSector(img[:sectorSize], label)
+
+	fat := make([]byte, sectorsPerFAT*sectorSize)
+	setFAT(fat, 0, 0xf00|mediaDescriptor)
+	setFAT(fat, 1, endOfChain)
+
+	root := img[(reservedSectors+fatCount*sectorsPerFAT)*sectorSize : dataStart*sectorSize]
+	shortNames := make(map[string]bool)
+	entry := 0
+	cluster := firstCluster
+	for _, f := range files {
+		short, err := shortName(f.Name, shortNames)
+		if err != nil {
+			return err
+		}
+		if !isShortName(f.Name) {
+			for _, e := range longNameDirEntries(f.Name, short) {
+				copy(root[entry*dirEntrySize:], e)
+				entry++
+			}
+		}
+
+		start := 0
+		clusters := (len(f.Content) + sectorSize - 1) / sectorSize
+		if clusters > 0 {
+			start = cluster
+			for i := 0; i < clusters; i++ {
+				next := cluster + 1
+				if i == clusters-1 {
+					next = endOfChain
+				}
+				setFAT(fat, cluster, next)
+				cluster++
+			}
+			copy(img[(dataStart+start-firstCluster)*sectorSize:], f.Content)
+		}
+
+		e := root[entry*dirEntrySize : (entry+1)*dirEntrySize]
+		copy(e[0:11], short)
+		e[11] = attrArchive
+		binary.LittleEndian.PutUint16(e[16:18], fatDate)
+		binary.LittleEndian.PutUint16(e[18:20], fatDate)
+		binary.LittleEndian.PutUint16(e[24:26], fatDate)
+		binary.LittleEndian.PutUint16(e[26:28], uint16(start))
+		binary.LittleEndian.PutUint32(e[28:32], uint32(len(f.Content)))
+		entry++
+	}
+
+	for i := 0; i < fatCount; i++ {
+		copy(img[(reservedSectors+i*sectorsPerFAT)*sectorSize:], fat)
+	}
+	_, err := w.Write(img)
+	return err
+}
+
+func writeBootSector(b []byte, label string) {
+	// a jump over the BIOS parameter block to an infinite loop
+	copy(b[0:3], []byte{0xeb, 0x3c, 0x90})
+	copy(b[3:11], "INDUCTOR")
+	binary.LittleEndian.PutUint16(b[11:13], sectorSize)
+	b[13] = 1 // sectors per cluster
+	binary.LittleEndian.PutUint16(b[14:16], reservedSectors)
+	b[16] = fatCount
+	binary.LittleEndian.PutUint16(b[17:19], rootEntries)
+	binary.LittleEndian.PutUint16(b[19:21], totalSectors)
+	b[21] = mediaDescriptor
+	binary.LittleEndian.PutUint16(b[22:24], sectorsPerFAT)
+	binary.LittleEndian.PutUint16(b[24:26], sectorsPerTrack)
+	binary.LittleEndian.PutUint16(b[26:28], heads)
+	b[38] = 0x29 // extended boot signature
+	binary.LittleEndian.PutUint32(b[39:43], 0x1d0c7012)
+	copy(b[43:54], fmt.Sprintf("%-11.11s", strings.ToUpper(label)))
+	copy(b[54:62], "FAT12   ")
+	copy(b[62:64], []byte{0xeb, 0xfe})
+	b[510], b[511] = 0x55, 0xaa
+}
+
+// setFAT sets the 12 bit FAT entry of the cluster
+func setFAT(fat []byte, cluster, value int) {
+	off := cluster * 3 / 2
+	if cluster%2 == 0 {
+		fat[off] = byte(value)
+		fat[off+1] = fat[off+1]&0xf0 | byte(value>>8)&0x0f
+	} else {
+		fat[off] = fat[off]&0x0f | byte(value<<4)
+		fat[off+1] = byte(value >> 4)
+	}
+}
+
+// shortName creates a unique 8.3 name, e.g. AUTOUN~1XML for Autounattend.xml
+func shortName(name string, used map[string]bool) ([]byte, error) {
+	base, ext := name, ""
+	if i := strings.LastIndex(name, "."); i > 0 {
+		base, ext = name[:i], name[i+1:]
+	}
+	upperBase, upperExt := strings.ToUpper(base), strings.ToUpper(ext)
+	base, ext = shortChars(base), shortChars(ext)
+	if len(ext) > 3 {
+		ext = ext[:3]
+	}
+	// a name which only differs from an 8.3 name by case keeps its name
+	if len(base) > 0 && base == upperBase && ext == upperExt && len(base) <= 8 {
+		short := fmt.Sprintf("%-8s%-3s", base, ext)
+		if !used[short] {
+			used[short] = true
+			return []byte(short), nil
+		}
+	}
+	for n := 1; n < 1000000; n++ {
+		tail := fmt.Sprintf("~%d", n)
+		b := base
+		if len(b) > 8-len(tail) {
+			b = b[:8-len(tail)]
+		}
+		short := fmt.Sprintf("%-8s%-3s", b+tail, ext)
+		if !used[short] {
+			used[short] = true
+			return []byte(short), nil
+		}
+	}
+	return nil, fmt.Errorf("Couldn't create a short name for %s", name)
+}
+
+// shortChars upper cases the name, removing characters 8.3 names can't have
+func shortChars(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("!#$%&'()-@^_`{}~", r):
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// isShortName returns true when the name is already an upper case 8.3 name
+func isShortName(name string) bool {
+	base, ext := name, ""
+	if i := strings.LastIndex(name, "."); i > 0 {
+		base, ext = name[:i], name[i+1:]
+	}
+	return len(base) > 0 && len(base) <= 8 && len(ext) <= 3 &&
+		shortChars(base) == base && shortChars(ext) == ext
+}
+
+func longNameEntries(name string) int {
+	if isShortName(name) {
+		return 0
+	}
+	return (len(utf16.Encode([]rune(name))) + longNameChars - 1) / longNameChars
+}
+
+// longNameDirEntries creates the VFAT long name entries which precede the
+// short name entry, in the order they're stored
+func longNameDirEntries(name string, short []byte) [][]byte {
+	var sum byte
+	for _, c := range short {
+		sum = (sum&1)<<7 + sum>>1 + c
+	}
+
+	chars := utf16.Encode([]rune(name))
+	count := longNameEntries(name)
+	// the name is null terminated and padded with 0xffff
+	padded := make([]uint16, count*longNameChars)
+	for i := range padded {
+		switch {
+		case i < len(chars):
+			padded[i] = chars[i]
+		case i == len(chars):
+			padded[i] = 0
+		default:
+			padded[i] = 0xffff
+		}
+	}
+
+	entries := make([][]byte, count)
+	for i := 0; i < count; i++ {
+		e := make([]byte, dirEntrySize)
+		seq := byte(i + 1)
+		if i == count-1 {
+			seq |= lastLongNameEntry
+		}
+		e[0] = seq
+		e[11] = attrLongName
+		e[13] = sum
+		part := padded[i*longNameChars : (i+1)*longNameChars]
+		offsets := []int{1, 3, 5, 7, 9, 14, 16, 18, 20, 22, 24, 28, 30}
+		for j, off := range offsets {
+			binary.LittleEndian.PutUint16(e[off:off+2], part[j])
+		}
+		// the last part of the name is stored first
+		entries[count-1-i] = e
+	}
+	return entries
+}
@@ -0,0 +1,13 @@
+package floppy_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestFloppy(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Floppy Suite")
+}
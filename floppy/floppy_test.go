@@ -0,0 +1,194 @@
+package floppy_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/joefitzgerald/inductor/floppy"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// readRoot lists the files in the root directory of a FAT12 floppy image with
+// their long name if they have one, following the FAT cluster chains
+func readRoot(img []byte) map[string][]byte {
+	fat := img[512 : 512+9*512]
+	next := func(cluster int) int {
+		off := cluster * 3 / 2
+		v := int(binary.LittleEndian.Uint16(fat[off : off+2]))
+		if cluster%2 == 0 {
+			return v & 0xfff
+		}
+		return v >> 4
+	}
+
+	files := make(map[string][]byte)
+	root := img[19*512 : 33*512]
+	longName := ""
+	for i := 0; i < 224; i++ {
+		e := root[i*32 : (i+1)*32]
+		if e[0] == 0 {
+			break
+		}
+		if e[11] == 0x0f {
+			var chars []uint16
+			for _, off := range []int{1, 3, 5, 7, 9, 14, 16, 18, 20, 22, 24, 28, 30} {
+				c := binary.LittleEndian.Uint16(e[off : off+2])
+				if c == 0 || c == 0xffff {
+					break
+				}
+				chars = append(chars, c)
+			}
+			longName = string(utf16.Decode(chars)) + longName
+			continue
+		}
+		name := longName
+		if len(name) == 0 {
+			name = strings.TrimSpace(string(e[0:8]))
+			if ext := strings.TrimSpace(string(e[8:11])); len(ext) > 0 {
+				name += "." + ext
+			}
+		}
+		longName = ""
+
+		size := int(binary.LittleEndian.Uint32(e[28:32]))
+		var content []byte
+		for cluster := int(binary.LittleEndian.Uint16(e[26:28])); cluster >= 2 && cluster < 0xff8; cluster = next(cluster) {
+			start := (33 + cluster - 2) * 512
+			content = append(content, img[start:start+512]...)
+		}
+		if len(content) > size {
+			content = content[:size]
+		}
+		files[name] = content
+	}
+	return files
+}
+
+var _ = Describe("Floppy", func() {
+	var (
+		err   error
+		img   bytes.Buffer
+		files []floppy.File
+	)
+
+	BeforeEach(func() {
+		img.Reset()
+		files = []floppy.File{
+			{Name: "Autounattend.xml", Content: []byte(strings.Repeat("<unattend/>", 100))},
+			{Name: "README.TXT", Content: []byte("readme")},
+			{Name: "enable-winrm-over-a-long-name.ps1", Content: []byte("Enable-PSRemoting")},
+			{Name: "empty.cmd", Content: []byte{}},
+		}
+	})
+	JustBeforeEach(func() {
+		err = floppy.Write(&img, "inductor", files)
+	})
+
+	It("should write a 1.44MB image", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(img.Len()).To(Equal(1474560))
+		b := img.Bytes()
+		Expect(binary.LittleEndian.Uint16(b[11:13])).To(Equal(uint16(512)))
+		Expect(binary.LittleEndian.Uint16(b[19:21])).To(Equal(uint16(2880)))
+		Expect(string(b[43:54])).To(Equal("INDUCTOR   "))
+		Expect(string(b[54:62])).To(Equal("FAT12   "))
+		Expect(b[510:512]).To(Equal([]byte{0x55, 0xaa}))
+	})
+	It("should write the same FAT twice", func() {
+		b := img.Bytes()
+		Expect(b[512 : 512+9*512]).To(Equal(b[512+9*512 : 512+18*512]))
+		Expect(b[512:515]).To(Equal([]byte{0xf0, 0xff, 0xff}))
+	})
+	It("should contain every file by its long name", func() {
+		Expect(readRoot(img.Bytes())).To(Equal(map[string][]byte{
+			"Autounattend.xml":                  files[0].Content,
+			"README.TXT":                        files[1].Content,
+			"enable-winrm-over-a-long-name.ps1": files[2].Content,
+			"empty.cmd":                         nil,
+		}))
+	})
+	It("should create unique short names", func() {
+		root := img.Bytes()[19*512:]
+		Expect(string(root[2*32 : 2*32+11])).To(Equal("AUTOUN~1XML"))
+		Expect(string(root[3*32 : 3*32+11])).To(Equal("README  TXT"))
+		Expect(string(root[9*32 : 9*32+11])).To(Equal("EMPTY   CMD"))
+	})
+
+	It("should match the FAT12 layout byte for byte", func() {
+		Expect(err).NotTo(HaveOccurred())
+		b := img.Bytes()
+		// the boot sector up to the end of the extended BIOS parameter block
+		Expect(b[0:64]).To(Equal([]byte{
+			0xeb, 0x3c, 0x90, 'I', 'N', 'D', 'U', 'C', 'T', 'O', 'R',
+			0x00, 0x02, // bytes per sector
+			0x01,       // sectors per cluster
+			0x01, 0x00, // reserved sectors
+			0x02,       // FATs
+			0xe0, 0x00, // root directory entries
+			0x40, 0x0b, // total sectors
+			0xf0,       // media descriptor
+			0x09, 0x00, // sectors per FAT
+			0x12, 0x00, // sectors per track
+			0x02, 0x00, // heads
+			0x00, 0x00, 0x00, 0x00, // hidden sectors
+			0x00, 0x00, 0x00, 0x00, // large total sectors
+			0x00, 0x00, // drive number and reserved
+			0x29,                   // extended boot signature
+			0x12, 0x70, 0x0c, 0x1d, // volume serial number
+			'I', 'N', 'D', 'U', 'C', 'T', 'O', 'R', ' ', ' ', ' ',
+			'F', 'A', 'T', '1', '2', ' ', ' ', ' ',
+			0xeb, 0xfe,
+		}))
+		// the media byte and end of chain, then the chains 2-3-4, 5 and 6
+		Expect(b[0x200:0x20c]).To(Equal([]byte{0xf0, 0xff, 0xff, 0x03, 0x40, 0x00, 0xff, 0xff, 0xff, 0xff, 0x0f, 0x00}))
+		// README.TXT is the fourth root directory entry, after the two long
+		// name entries and the short name entry of Autounattend.xml
+		Expect(b[0x2660:0x2680]).To(Equal([]byte{
+			'R', 'E', 'A', 'D', 'M', 'E', ' ', ' ', 'T', 'X', 'T',
+			0x20,                   // archive attribute
+			0x00, 0x00, 0x00, 0x00, // reserved and creation time
+			0x21, 0x00, // creation date, 1980-01-01
+			0x21, 0x00, // access date
+			0x00, 0x00, // high cluster
+			0x00, 0x00, // modification time
+			0x21, 0x00, // modification date
+			0x05, 0x00, // first cluster
+			0x06, 0x00, 0x00, 0x00, // size
+		}))
+		// cluster 5 is the 37th sector, after the boot sector, FATs and root
+		Expect(string(b[0x4800:0x4806])).To(Equal("readme"))
+	})
+
+	Context("with more than a floppy holds", func() {
+		BeforeEach(func() {
+			files = append(files, floppy.File{Name: "big.zip", Content: make([]byte, 1500*1024)})
+		})
+		It("should error", func() {
+			Expect(err).To(MatchError("The floppy files need 1502 KB but a 1.44MB floppy only holds 1423 KB"))
+		})
+	})
+	Context("with more files than the root directory holds", func() {
+		BeforeEach(func() {
+			// each name needs two long name entries and a short name entry
+			for i := 0; i < 100; i++ {
+				files = append(files, floppy.File{Name: fmt.Sprintf("script-%03d.ps1", i)})
+			}
+		})
+		It("should error", func() {
+			Expect(err).To(MatchError("The floppy files need 310 directory entries but a floppy's root directory only holds 224"))
+		})
+	})
+	Context("with duplicate names", func() {
+		BeforeEach(func() {
+			files = append(files, floppy.File{Name: "autounattend.XML"})
+		})
+		It("should error", func() {
+			Expect(err).To(MatchError("More than one floppy file is named autounattend.XML"))
+		})
+	})
+})
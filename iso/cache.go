@@ -0,0 +1,225 @@
+package iso
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// partialExt is appended to an ISO while it's downloading
+const partialExt = ".part"
+
+// Cache stores downloaded ISOs in a directory keyed by their checksum, so an
+// ISO shared by several operating systems is only downloaded once
+type Cache struct {
+	Dir    string
+	Client *http.Client
+}
+
+// NewCache creates a cache of ISOs in the directory
+func NewCache(dir string) *Cache {
+	return &Cache{
+		Dir:    dir,
+		Client: http.DefaultClient,
+	}
+}
+
+// Path is where the ISO with the checksum is stored
+func (c *Cache) Path(checksumType, checksum string) string {
+	name := fmt.Sprintf("%s-%s.iso", strings.ToLower(checksumType), strings.ToLower(checksum))
+	return filepath.Join(c.Dir, name)
+}
+
+// Lookup returns the path of the cached ISO if it has been fetched, marking
+// it as recently used so it isn't pruned
+func (c *Cache) Lookup(checksumType, checksum string) (string, bool) {
+	path := c.Path(checksumType, checksum)
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return path, true
+}
+
+// Fetch downloads the ISO from the first remote URL which succeeds unless it's
+// already cached, returning the path of the cached ISO. An interrupted
+// download is resumed with a HTTP range request and the ISO is only added to
+// the cache once its checksum has been verified.
+func (c *Cache) Fetch(isoURLs []string, checksumType, checksum string) (string, error) {
+	if len(checksum) == 0 {
+		return "", fmt.Errorf("An ISO checksum is required to cache the ISO")
+	}
+	if _, err := NewHash(checksumType); err != nil {
+		return "", err
+	}
+	if path, ok := c.Lookup(checksumType, checksum); ok {
+		return path, nil
+	}
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return "", err
+	}
+
+	errs := []string{}
+	for _, isoURL := range isoURLs {
+		if _, ok := LocalPath(isoURL); ok {
+			continue
+		}
+		path, err := c.fetch(isoURL, checksumType, checksum)
+		if err == nil {
+			return path, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	if len(errs) == 0 {
+		return "", fmt.Errorf("No remote ISO URLs to fetch")
+	}
+	return "", fmt.Errorf("Couldn't fetch the ISO: %s", strings.Join(errs, ", "))
+}
+
+func (c *Cache) fetch(isoURL, checksumType, checksum string) (string, error) {
+	path := c.Path(checksumType, checksum)
+	partial := path + partialExt
+	if err := c.download(isoURL, partial); err != nil {
+		return "", err
+	}
+
+	computed, err := FileChecksum(partial, checksumType)
+	if err != nil {
+		return "", err
+	}
+	if !ChecksumEqual(computed, checksum) {
+		// the partial download is corrupt, so start again next time
+		os.Remove(partial)
+		return "", fmt.Errorf("%s has a %s checksum of %s, expected %s", isoURL, checksumType, computed, checksum)
+	}
+	return path, os.Rename(partial, path)
+}
+
+// download writes the URL to the file, resuming from the end of the file
+// when the server supports range requests
+func (c *Cache) download(isoURL, path string) (err error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// a failed flush of the written data fails the download
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.get(isoURL, offset)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusPartialContent && !rangeStartsAt(resp.Header.Get("Content-Range"), offset) {
+		// the server sent a different range, so start from the beginning
+		resp.Body.Close()
+		if resp, err = c.get(isoURL, 0); err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+	}
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the previous download was already complete
+		return nil
+	case http.StatusOK:
+		// the server ignored the range, so start from the beginning
+		if _, err = f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if err = f.Truncate(0); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%s returned %s", isoURL, resp.Status)
+	}
+	_, err = io.Copy(f, resp.Body)
+	return err
+}
+
+// get requests the URL, from the offset onwards when it's past the start
+func (c *Cache) get(isoURL string, offset int64) (*http.Response, error) {
+	req, err := http.NewRequest("GET", isoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	return c.Client.Do(req)
+}
+
+// rangeStartsAt returns true when the Content-Range header of a partial
+// response starts at the offset, e.g. bytes 1000-9215/9216
+func rangeStartsAt(contentRange string, offset int64) bool {
+	var start int64
+	if _, err := fmt.Sscanf(contentRange, "bytes %d-", &start); err != nil {
+		return false
+	}
+	return start == offset
+}
+
+// Prune removes cached ISOs and partial downloads which haven't been used for
+// longer than the max age, and then the least recently used ISOs until the
+// cache is no larger than the max size. A zero max age or size is no limit.
+// The paths of the removed files are returned.
+func (c *Cache) Prune(maxAge time.Duration, maxSize int64) ([]string, error) {
+	infos, err := ioutil.ReadDir(c.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// least recently used first
+	sort.Sort(byModTime(infos))
+	var size int64
+	for _, info := range infos {
+		size += info.Size()
+	}
+
+	removed := []string{}
+	now := time.Now()
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		expired := maxAge > 0 && now.Sub(info.ModTime()) > maxAge
+		tooBig := maxSize > 0 && size > maxSize
+		if !expired && !tooBig {
+			continue
+		}
+		path := filepath.Join(c.Dir, info.Name())
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		size -= info.Size()
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
+
+type byModTime []os.FileInfo
+
+func (s byModTime) Len() int           { return len(s) }
+func (s byModTime) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byModTime) Less(i, j int) bool { return s[i].ModTime().Before(s[j].ModTime()) }
@@ -0,0 +1,148 @@
+package iso_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/joefitzgerald/inductor/iso"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cache", func() {
+	var (
+		err      error
+		dir      string
+		cache    *iso.Cache
+		server   *httptest.Server
+		content  []byte
+		checksum string
+		ranges   []string
+	)
+
+	BeforeEach(func() {
+		dir, err = ioutil.TempDir("", "inductor")
+		Expect(err).NotTo(HaveOccurred())
+		cache = iso.NewCache(dir)
+		content = bytes.Repeat([]byte("windows10"), 1024)
+		checksum, err = iso.Checksum(bytes.NewReader(content), "sha256")
+		Expect(err).NotTo(HaveOccurred())
+		ranges = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ranges = append(ranges, r.Header.Get("Range"))
+			if r.URL.Path == "/from-start.iso" && len(r.Header.Get("Range")) > 0 {
+				// a partial response which ignores the requested offset
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(content)-1, len(content)))
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write(content)
+				return
+			}
+			if r.URL.Path != "/windows10.iso" && r.URL.Path != "/from-start.iso" {
+				http.NotFound(w, r)
+				return
+			}
+			http.ServeContent(w, r, "windows10.iso", time.Time{}, bytes.NewReader(content))
+		}))
+	})
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dir)
+	})
+
+	Describe("Fetch", func() {
+		var path string
+		urls := func() []string {
+			return []string{"file:///nas/windows10.iso", server.URL + "/missing.iso", server.URL + "/windows10.iso"}
+		}
+
+		It("should download and verify the ISO", func() {
+			path, err = cache.Fetch(urls(), "sha256", checksum)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(filepath.Join(dir, "sha256-"+checksum+".iso")))
+			Expect(ioutil.ReadFile(path)).To(Equal(content))
+			Expect(filepath.Join(dir, "sha256-"+checksum+".iso.part")).NotTo(BeAnExistingFile())
+		})
+		It("should not download a cached ISO again", func() {
+			_, err = cache.Fetch(urls(), "sha256", checksum)
+			Expect(err).NotTo(HaveOccurred())
+			ranges = nil
+			path, err = cache.Fetch(urls(), "sha256", checksum)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ranges).To(BeEmpty())
+			cached, ok := cache.Lookup("sha256", checksum)
+			Expect(ok).To(BeTrue())
+			Expect(cached).To(Equal(path))
+		})
+		It("should resume an interrupted download", func() {
+			partial := cache.Path("sha256", checksum) + ".part"
+			Expect(ioutil.WriteFile(partial, content[:1000], 0644)).To(Succeed())
+			path, err = cache.Fetch(urls(), "sha256", checksum)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ranges).To(ContainElement("bytes=1000-"))
+			Expect(ioutil.ReadFile(path)).To(Equal(content))
+		})
+		It("should start again when the partial content doesn't start at the offset", func() {
+			partial := cache.Path("sha256", checksum) + ".part"
+			Expect(ioutil.WriteFile(partial, content[:1000], 0644)).To(Succeed())
+			path, err = cache.Fetch([]string{server.URL + "/from-start.iso"}, "sha256", checksum)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ranges).To(Equal([]string{"bytes=1000-", ""}))
+			Expect(ioutil.ReadFile(path)).To(Equal(content))
+		})
+		It("should finish a download which was already complete", func() {
+			partial := cache.Path("sha256", checksum) + ".part"
+			Expect(ioutil.WriteFile(partial, content, 0644)).To(Succeed())
+			path, err = cache.Fetch(urls(), "sha256", checksum)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ioutil.ReadFile(path)).To(Equal(content))
+		})
+		It("should discard an ISO with the wrong checksum", func() {
+			wrong := "0000000000000000000000000000000000000000000000000000000000000000"
+			_, err = cache.Fetch(urls(), "sha256", wrong)
+			Expect(err).To(MatchError(ContainSubstring("/windows10.iso has a sha256 checksum of " + checksum)))
+			Expect(err).To(MatchError(ContainSubstring("/missing.iso returned 404 Not Found")))
+			Expect(cache.Path("sha256", wrong)).NotTo(BeAnExistingFile())
+			Expect(cache.Path("sha256", wrong) + ".part").NotTo(BeAnExistingFile())
+		})
+		It("should require a checksum", func() {
+			_, err = cache.Fetch(urls(), "sha256", "")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Prune", func() {
+		var removed []string
+		BeforeEach(func() {
+			now := time.Now()
+			for i, name := range []string{"sha1-old.iso", "sha1-recent.iso", "sha1-new.iso"} {
+				path := filepath.Join(dir, name)
+				Expect(ioutil.WriteFile(path, make([]byte, 100), 0644)).To(Succeed())
+				age := time.Duration(2-i) * 24 * time.Hour
+				Expect(os.Chtimes(path, now.Add(-age), now.Add(-age))).To(Succeed())
+			}
+		})
+		It("should remove entries older than the max age", func() {
+			removed, err = cache.Prune(36*time.Hour, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(removed).To(Equal([]string{filepath.Join(dir, "sha1-old.iso")}))
+		})
+		It("should remove the least recently used entries over the max size", func() {
+			removed, err = cache.Prune(0, 150)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(removed).To(Equal([]string{filepath.Join(dir, "sha1-old.iso"), filepath.Join(dir, "sha1-recent.iso")}))
+			Expect(filepath.Join(dir, "sha1-new.iso")).To(BeARegularFile())
+		})
+		It("should not error for a missing cache", func() {
+			removed, err = iso.NewCache(filepath.Join(dir, "missing")).Prune(time.Hour, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(removed).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,57 @@
+package iso
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+// NewHash creates the hash for a Packer ISO checksum type, one of md5, sha1,
+// sha256 or sha512
+func NewHash(checksumType string) (hash.Hash, error) {
+	switch strings.ToLower(checksumType) {
+	case "md5":
+		return md5.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	}
+	return nil, fmt.Errorf("Unknown checksum type '%s', expected one of md5, sha1, sha256 or sha512", checksumType)
+}
+
+// Checksum hashes the content, returning the lower case hex checksum
+func Checksum(content io.Reader, checksumType string) (string, error) {
+	h, err := NewHash(checksumType)
+	if err != nil {
+		return "", err
+	}
+	if _, err = io.Copy(h, content); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// FileChecksum hashes the file at the path
+func FileChecksum(path, checksumType string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return Checksum(f, checksumType)
+}
+
+// ChecksumEqual compares checksums ignoring case
+func ChecksumEqual(a, b string) bool {
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
@@ -0,0 +1,76 @@
+package iso_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joefitzgerald/inductor/iso"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Checksum", func() {
+	It("should hash with every Packer checksum type", func() {
+		checksums := map[string]string{
+			"md5":    "900150983cd24fb0d6963f7d28e17f72",
+			"sha1":   "a9993e364706816aba3e25717850c26c9cd0d89d",
+			"sha256": "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+			"SHA512": "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f",
+		}
+		for checksumType, expected := range checksums {
+			Expect(iso.Checksum(strings.NewReader("abc"), checksumType)).To(Equal(expected))
+		}
+	})
+	It("should error with an unknown checksum type", func() {
+		_, err := iso.Checksum(strings.NewReader("abc"), "crc32")
+		Expect(err).To(MatchError("Unknown checksum type 'crc32', expected one of md5, sha1, sha256 or sha512"))
+	})
+	It("should compare checksums ignoring case", func() {
+		Expect(iso.ChecksumEqual("A9993E36", "a9993e36")).To(BeTrue())
+		Expect(iso.ChecksumEqual("a9993e36", "a9993e37")).To(BeFalse())
+	})
+
+	Describe("local ISOs", func() {
+		var dir string
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "inductor")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "windows10.iso"), []byte("abc"), 0644)).To(Succeed())
+		})
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+		It("should hash a file", func() {
+			Expect(iso.FileChecksum(filepath.Join(dir, "windows10.iso"), "sha1")).To(Equal("a9993e364706816aba3e25717850c26c9cd0d89d"))
+		})
+		It("should find the first local ISO which exists", func() {
+			path, ok := iso.FindLocal([]string{
+				"http://mirror/windows10.iso",
+				"file://" + filepath.ToSlash(filepath.Join(dir, "missing.iso")),
+				"file://" + filepath.ToSlash(filepath.Join(dir, "windows10.iso")),
+			})
+			Expect(ok).To(BeTrue())
+			Expect(path).To(Equal(filepath.Join(dir, "windows10.iso")))
+		})
+		It("should not find remote ISOs", func() {
+			_, ok := iso.FindLocal([]string{"http://mirror/windows10.iso"})
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("LocalPath", func() {
+		It("should treat paths without a scheme as local", func() {
+			path, ok := iso.LocalPath("isos/windows10.iso")
+			Expect(ok).To(BeTrue())
+			Expect(path).To(Equal(filepath.FromSlash("isos/windows10.iso")))
+		})
+		It("should not treat remote URLs as local", func() {
+			_, ok := iso.LocalPath("https://mirror/windows10.iso")
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
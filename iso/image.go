@@ -0,0 +1,159 @@
+package iso
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// sectorSize is the size of an ISO9660 logical sector and UDF logical block
+const sectorSize = 2048
+
+// maxDirectorySize limits the directory content read into memory
+const maxDirectorySize = 16 << 20
+
+// FileSystem is the file system of an ISO image
+type FileSystem interface {
+	// Open returns the content of the file at the slash separated path,
+	// ignoring case
+	Open(name string) (*io.SectionReader, error)
+}
+
+// OpenImage reads the file system of an ISO image. The UDF file system is used
+// when there is one, as Windows ISOs only have a placeholder README in their
+// ISO9660 file system, otherwise the Joliet or plain ISO9660 file system.
+func OpenImage(r io.ReaderAt) (FileSystem, error) {
+	if fs, ok, err := openUDF(r); ok || err != nil {
+		return fs, err
+	}
+	return openISO9660(r)
+}
+
+// entry is a file or directory in an image
+type entry struct {
+	name    string
+	dir     bool
+	size    int64
+	extents []extent
+}
+
+// extent is a contiguous run of bytes in the image, an extent with a negative
+// offset isn't recorded and reads as zeros
+type extent struct {
+	offset int64
+	length int64
+}
+
+// dirReader lists the entries of a directory
+type dirReader func(dir entry) ([]entry, error)
+
+// lookup walks the slash separated path from the root, ignoring case
+func lookup(root entry, name string, readDir dirReader) (entry, error) {
+	current := root
+	for _, part := range strings.Split(strings.Trim(path.Clean("/"+name), "/"), "/") {
+		if len(part) == 0 {
+			continue
+		}
+		if !current.dir {
+			return entry{}, fmt.Errorf("%s isn't a directory in the image", current.name)
+		}
+		entries, err := readDir(current)
+		if err != nil {
+			return entry{}, err
+		}
+		found := false
+		for _, e := range entries {
+			if strings.EqualFold(e.name, part) {
+				current, found = e, true
+				break
+			}
+		}
+		if !found {
+			return entry{}, fmt.Errorf("Couldn't find %s in the image", name)
+		}
+	}
+	return current, nil
+}
+
+// readAll reads the entire content of the directory entry
+func readAll(r io.ReaderAt, e entry) ([]byte, error) {
+	if e.size < 0 || e.size > maxDirectorySize {
+		return nil, fmt.Errorf("Invalid image, the %s directory size is %d", e.name, e.size)
+	}
+	buf := make([]byte, e.size)
+	_, err := newExtentReader(r, e).ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("Invalid image, couldn't read the %s directory: %s", e.name, err)
+	}
+	return buf, nil
+}
+
+// extentReader reads a file made of one or more extents in the image
+type extentReader struct {
+	r       io.ReaderAt
+	extents []extent
+	size    int64
+}
+
+func newExtentReader(r io.ReaderAt, e entry) *extentReader {
+	return &extentReader{r: r, extents: e.extents, size: e.size}
+}
+
+func (er *extentReader) ReadAt(p []byte, off int64) (int, error) {
+	if off >= er.size {
+		return 0, io.EOF
+	}
+	if remaining := er.size - off; int64(len(p)) > remaining {
+		p = p[:remaining]
+		n, err := er.readAt(p, off)
+		if err == nil {
+			err = io.EOF
+		}
+		return n, err
+	}
+	return er.readAt(p, off)
+}
+
+func (er *extentReader) readAt(p []byte, off int64) (int, error) {
+	n := 0
+	start := int64(0)
+	for _, ext := range er.extents {
+		if n == len(p) {
+			break
+		}
+		end := start + ext.length
+		if off+int64(n) < end {
+			within := off + int64(n) - start
+			chunk := p[n:]
+			if int64(len(chunk)) > ext.length-within {
+				chunk = chunk[:ext.length-within]
+			}
+			if ext.offset < 0 {
+				for i := range chunk {
+					chunk[i] = 0
+				}
+				n += len(chunk)
+			} else {
+				read, err := er.r.ReadAt(chunk, ext.offset+within)
+				n += read
+				if err != nil {
+					return n, err
+				}
+			}
+		}
+		start = end
+	}
+	if n < len(p) {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, nil
+}
+
+// section returns a reader of the entry's content
+func section(r io.ReaderAt, e entry) (*io.SectionReader, error) {
+	if e.dir {
+		return nil, fmt.Errorf("%s is a directory", e.name)
+	}
+	return io.NewSectionReader(newExtentReader(r, e), 0, e.size), nil
+}
@@ -0,0 +1,139 @@
+package iso_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/joefitzgerald/inductor/iso"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fixture reads a gzipped ISO image from the testdata directory
+func fixture(name string) *bytes.Reader {
+	f, err := os.Open(filepath.Join("testdata", name))
+	Expect(err).NotTo(HaveOccurred())
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	Expect(err).NotTo(HaveOccurred())
+	content, err := ioutil.ReadAll(gz)
+	Expect(err).NotTo(HaveOccurred())
+	return bytes.NewReader(content)
+}
+
+// fixtureBytes reads a gzipped ISO image from the testdata directory
+func fixtureBytes(name string) []byte {
+	content, err := ioutil.ReadAll(fixture(name))
+	Expect(err).NotTo(HaveOccurred())
+	return content
+}
+
+var _ = Describe("Image", func() {
+	var (
+		err error
+		fs  iso.FileSystem
+	)
+
+	readFile := func(name string) []byte {
+		r, err := fs.Open(name)
+		Expect(err).NotTo(HaveOccurred())
+		content, err := ioutil.ReadAll(r)
+		Expect(err).NotTo(HaveOccurred())
+		return content
+	}
+
+	Context("with a UDF file system", func() {
+		BeforeEach(func() {
+			fs, err = iso.OpenImage(fixture("udf.iso.gz"))
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("should read a file split across extents", func() {
+			content := readFile("sources/install.wim")
+			Expect(content[:8]).To(Equal([]byte("MSWIM\x00\x00\x00")))
+			Expect(len(content)).To(BeNumerically(">", 2048))
+		})
+		It("should ignore case", func() {
+			Expect(readFile("/SOURCES/Install.WIM")).To(Equal(readFile("sources/install.wim")))
+		})
+		It("should not find deleted files", func() {
+			_, err = fs.Open("deleted.txt")
+			Expect(err).To(MatchError("Couldn't find deleted.txt in the image"))
+		})
+		It("should not open directories", func() {
+			_, err = fs.Open("sources")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("with a Joliet file system", func() {
+		BeforeEach(func() {
+			fs, err = iso.OpenImage(fixture("joliet.iso.gz"))
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("should read files by their Joliet names", func() {
+			content := readFile("sources/install.esd")
+			Expect(content[:8]).To(Equal([]byte("MSWIM\x00\x00\x00")))
+		})
+	})
+
+	Context("without a file system", func() {
+		It("should error", func() {
+			_, err = iso.OpenImage(bytes.NewReader(make([]byte, 64*1024)))
+			Expect(err).To(MatchError("Couldn't find an ISO9660 or UDF file system in the image"))
+		})
+	})
+
+	Context("with a truncated image", func() {
+		It("should error instead of reading past the end", func() {
+			for _, name := range []string{"udf.iso.gz", "joliet.iso.gz"} {
+				content := fixtureBytes(name)
+				_, err = iso.InstallImages(bytes.NewReader(content[:len(content)/2]))
+				Expect(err).To(HaveOccurred())
+			}
+		})
+		It("should not panic wherever the image ends", func() {
+			for _, name := range []string{"udf.iso.gz", "joliet.iso.gz"} {
+				content := fixtureBytes(name)
+				for size := 0; size < len(content); size += 512 {
+					Expect(func() {
+						iso.InstallImages(bytes.NewReader(content[:size]))
+					}).NotTo(Panic())
+				}
+			}
+		})
+	})
+
+	Context("with a corrupt image", func() {
+		It("should error on a directory record name longer than the record", func() {
+			content := fixtureBytes("joliet.iso.gz")
+			// the root directory record name length of every volume descriptor
+			for sector := 16; content[sector*2048] != 255; sector++ {
+				content[sector*2048+156+32] = 255
+			}
+			_, err = iso.OpenImage(bytes.NewReader(content))
+			Expect(err).To(MatchError("Invalid ISO9660 directory record"))
+		})
+		It("should error on an invalid UDF block size", func() {
+			content := fixtureBytes("udf.iso.gz")
+			lvd := -1
+			for sector := 0; sector < 256; sector++ {
+				if binary.LittleEndian.Uint16(content[sector*2048:]) == 6 {
+					lvd = sector * 2048
+					break
+				}
+			}
+			Expect(lvd).NotTo(Equal(-1))
+			for _, size := range []uint32{0, 1000, 1 << 30} {
+				binary.LittleEndian.PutUint32(content[lvd+212:], size)
+				_, err = iso.OpenImage(bytes.NewReader(content))
+				Expect(err).To(MatchError(fmt.Sprintf("Invalid UDF logical block size %d", size)))
+			}
+		})
+	})
+})
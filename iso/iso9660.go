@@ -0,0 +1,170 @@
+package iso
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// ISO9660 volume descriptor types
+const (
+	primaryVolumeDescriptor       = 1
+	supplementaryVolumeDescriptor = 2
+	volumeDescriptorTerminator    = 255
+)
+
+// ISO9660 directory record flags
+const (
+	recordDirectory   = 0x02
+	recordMultiExtent = 0x80
+)
+
+var isoIdentifier = []byte("CD001")
+
+// iso9660 is an ISO9660 file system, using the Joliet extension's Unicode
+// names when there is one
+type iso9660 struct {
+	r      io.ReaderAt
+	root   entry
+	joliet bool
+}
+
+func openISO9660(r io.ReaderAt) (*iso9660, error) {
+	var primary, joliet []byte
+	for sector := int64(16); ; sector++ {
+		vd := make([]byte, sectorSize)
+		if _, err := r.ReadAt(vd, sector*sectorSize); err != nil {
+			return nil, errors.New("Couldn't find an ISO9660 or UDF file system in the image")
+		}
+		if !bytes.Equal(vd[1:6], isoIdentifier) {
+			return nil, errors.New("Couldn't find an ISO9660 or UDF file system in the image")
+		}
+		switch vd[0] {
+		case primaryVolumeDescriptor:
+			primary = vd
+		case supplementaryVolumeDescriptor:
+			// the Joliet UCS-2 levels 1, 2 and 3
+			if vd[88] == '%' && vd[89] == '/' && bytes.IndexByte([]byte("@CE"), vd[90]) >= 0 {
+				joliet = vd
+			}
+		}
+		if vd[0] == volumeDescriptorTerminator {
+			break
+		}
+	}
+
+	fs := &iso9660{r: r}
+	vd := primary
+	if joliet != nil {
+		vd, fs.joliet = joliet, true
+	}
+	if vd == nil {
+		return nil, errors.New("Couldn't find the ISO9660 primary volume descriptor")
+	}
+	// the root directory record
+	root, err := fs.parseRecord(vd[156 : 156+34])
+	if err != nil {
+		return nil, err
+	}
+	fs.root = root.entry
+	fs.root.name = "/"
+	return fs, nil
+}
+
+func (fs *iso9660) Open(name string) (*io.SectionReader, error) {
+	e, err := lookup(fs.root, name, fs.readDir)
+	if err != nil {
+		return nil, err
+	}
+	return section(fs.r, e)
+}
+
+func (fs *iso9660) readDir(dir entry) ([]entry, error) {
+	data, err := readAll(fs.r, dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := []isoEntry{}
+	for pos := 0; pos < len(data); {
+		length := int(data[pos])
+		if length == 0 {
+			// records don't span sectors, skip the padding to the next sector
+			pos = (pos/sectorSize + 1) * sectorSize
+			continue
+		}
+		if pos+length > len(data) || length < 34 {
+			return nil, errors.New("Invalid ISO9660 directory record")
+		}
+		record := data[pos : pos+length]
+		pos += length
+
+		nameLen := int(record[32])
+		if nameLen == 1 && record[33] <= 1 {
+			// the . and .. entries
+			continue
+		}
+		e, err := fs.parseRecord(record)
+		if err != nil {
+			return nil, err
+		}
+		// a file larger than 4GB is split across records with the same name
+		last := len(entries) - 1
+		if last >= 0 && entries[last].multiExtent && entries[last].name == e.name {
+			entries[last].extents = append(entries[last].extents, e.extents...)
+			entries[last].size += e.size
+			entries[last].multiExtent = e.multiExtent
+			continue
+		}
+		entries = append(entries, e)
+	}
+	result := make([]entry, len(entries))
+	for i, e := range entries {
+		result[i] = e.entry
+	}
+	return result, nil
+}
+
+// isoEntry is an entry which may continue in the next directory record
+type isoEntry struct {
+	entry
+	multiExtent bool
+}
+
+func (fs *iso9660) parseRecord(record []byte) (isoEntry, error) {
+	if len(record) < 34 || 33+int(record[32]) > len(record) {
+		return isoEntry{}, errors.New("Invalid ISO9660 directory record")
+	}
+	lba := int64(binary.LittleEndian.Uint32(record[2:6]))
+	size := int64(binary.LittleEndian.Uint32(record[10:14]))
+	flags := record[25]
+	nameLen := int(record[32])
+	return isoEntry{
+		entry: entry{
+			name:    fs.decodeName(record[33 : 33+nameLen]),
+			dir:     flags&recordDirectory != 0,
+			size:    size,
+			extents: []extent{{offset: lba * sectorSize, length: size}},
+		},
+		multiExtent: flags&recordMultiExtent != 0,
+	}, nil
+}
+
+// decodeName decodes a Joliet UCS-2 name or an ISO9660 name, removing the
+// version number and the trailing dot of names without an extension
+func (fs *iso9660) decodeName(raw []byte) string {
+	name := string(raw)
+	if fs.joliet {
+		u := make([]uint16, len(raw)/2)
+		for i := range u {
+			u[i] = binary.BigEndian.Uint16(raw[i*2:])
+		}
+		name = string(utf16.Decode(u))
+	}
+	if i := strings.LastIndex(name, ";"); i >= 0 {
+		name = name[:i]
+	}
+	return strings.TrimSuffix(name, ".")
+}
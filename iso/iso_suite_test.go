@@ -0,0 +1,13 @@
+package iso_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestISO(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ISO Suite")
+}
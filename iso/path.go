@@ -0,0 +1,44 @@
+package iso
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// LocalPath returns the file path of a file:// URL or a path without a URL
+// scheme, returning false for remote URLs
+func LocalPath(isoURL string) (string, bool) {
+	u, err := url.Parse(isoURL)
+	if err != nil {
+		return filepath.FromSlash(isoURL), true
+	}
+	switch {
+	case u.Scheme == "file":
+		// file://C:/isos/windows10.iso has a host of C: while
+		// file:///C:/isos/windows10.iso has a path of /C:/isos/windows10.iso
+		p := u.Host + u.Path
+		if len(p) > 2 && p[0] == '/' && p[2] == ':' {
+			p = p[1:]
+		}
+		return filepath.FromSlash(p), true
+	case len(u.Scheme) <= 1:
+		// no scheme or a Windows drive letter
+		return filepath.FromSlash(isoURL), true
+	}
+	return "", false
+}
+
+// FindLocal returns the path of the first local ISO which exists
+func FindLocal(isoURLs []string) (string, bool) {
+	for _, isoURL := range isoURLs {
+		path, ok := LocalPath(isoURL)
+		if !ok {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,299 @@
+package iso
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf16"
+)
+
+// UDF descriptor tag identifiers
+const (
+	tagPartition             = 5
+	tagLogicalVolume         = 6
+	tagTerminating           = 8
+	tagAnchorVolume          = 2
+	tagFileSet               = 256
+	tagFileIdentifier        = 257
+	tagFileEntry             = 261
+	tagExtendedFileEntry     = 266
+	anchorVolumeSector       = 256
+	maxVolumeDescriptors     = 64
+	minBlockSize             = 512
+	maxBlockSize             = 65536
+	fileCharacteristicDir    = 0x02
+	fileCharacteristicDel    = 0x04
+	fileCharacteristicParent = 0x08
+)
+
+// UDF ICB allocation descriptor types
+const (
+	shortAllocation    = 0
+	longAllocation     = 1
+	embeddedAllocation = 3
+)
+
+// udf is a UDF file system, as used by Windows install media. Only type 1
+// partition maps are supported, which is what Windows ISOs use.
+type udf struct {
+	r          io.ReaderAt
+	blockSize  int64
+	partitions []int64
+	root       entry
+}
+
+// openUDF reads the UDF file system, returning false when there isn't one
+func openUDF(r io.ReaderAt) (*udf, bool, error) {
+	anchor, err := readTag(r, anchorVolumeSector*sectorSize, sectorSize)
+	if err != nil || tagID(anchor) != tagAnchorVolume {
+		return nil, false, nil
+	}
+	// the main volume descriptor sequence extent
+	vdsLength := int64(binary.LittleEndian.Uint32(anchor[16:20]))
+	vdsSector := int64(binary.LittleEndian.Uint32(anchor[20:24]))
+
+	fs := &udf{r: r, blockSize: sectorSize}
+	partitionStarts := make(map[uint16]int64)
+	var lvd []byte
+	for i := int64(0); i < vdsLength/sectorSize && i < maxVolumeDescriptors; i++ {
+		d, err := readTag(r, (vdsSector+i)*sectorSize, sectorSize)
+		if err != nil {
+			return nil, true, err
+		}
+		switch tagID(d) {
+		case tagPartition:
+			number := binary.LittleEndian.Uint16(d[22:24])
+			partitionStarts[number] = int64(binary.LittleEndian.Uint32(d[188:192]))
+		case tagLogicalVolume:
+			lvd = d
+		}
+		if tagID(d) == tagTerminating {
+			break
+		}
+	}
+	if lvd == nil {
+		return nil, true, errors.New("Couldn't find the UDF logical volume descriptor")
+	}
+	fs.blockSize = int64(binary.LittleEndian.Uint32(lvd[212:216]))
+	// the block size is a power of two large enough for every descriptor
+	if fs.blockSize < minBlockSize || fs.blockSize > maxBlockSize || fs.blockSize&(fs.blockSize-1) != 0 {
+		return nil, true, fmt.Errorf("Invalid UDF logical block size %d", fs.blockSize)
+	}
+
+	// map partition reference numbers to the partition starting sectors
+	mapCount := int(binary.LittleEndian.Uint32(lvd[268:272]))
+	for i, pos := 0, 440; i < mapCount && pos+2 <= len(lvd); i++ {
+		mapType, mapLength := lvd[pos], int(lvd[pos+1])
+		if mapType != 1 || mapLength < 6 {
+			return nil, true, fmt.Errorf("Unsupported UDF partition map type %d", mapType)
+		}
+		if pos+mapLength > len(lvd) {
+			return nil, true, errors.New("Invalid UDF partition map")
+		}
+		number := binary.LittleEndian.Uint16(lvd[pos+4 : pos+6])
+		start, ok := partitionStarts[number]
+		if !ok {
+			return nil, true, fmt.Errorf("Couldn't find UDF partition %d", number)
+		}
+		fs.partitions = append(fs.partitions, start)
+		pos += mapLength
+	}
+
+	// the file set descriptor is in the logical volume contents use
+	fsdBlock, fsdPartition := longAD(lvd[248:264])
+	fsd, err := fs.readBlockTag(fsdBlock, fsdPartition)
+	if err != nil {
+		return nil, true, err
+	}
+	if tagID(fsd) != tagFileSet {
+		return nil, true, errors.New("Couldn't find the UDF file set descriptor")
+	}
+	rootBlock, rootPartition := longAD(fsd[400:416])
+	fs.root, err = fs.readFileEntry("/", true, rootBlock, rootPartition)
+	if err != nil {
+		return nil, true, err
+	}
+	return fs, true, nil
+}
+
+func (fs *udf) Open(name string) (*io.SectionReader, error) {
+	e, err := lookup(fs.root, name, fs.readDir)
+	if err != nil {
+		return nil, err
+	}
+	return section(fs.r, e)
+}
+
+// readDir parses the file identifier descriptors of the directory
+func (fs *udf) readDir(dir entry) ([]entry, error) {
+	data, err := readAll(fs.r, dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := []entry{}
+	for pos := 0; pos+38 <= len(data); {
+		fid := data[pos:]
+		if tagID(fid) != tagFileIdentifier {
+			return nil, errors.New("Invalid UDF file identifier descriptor")
+		}
+		characteristics := fid[18]
+		nameLen := int(fid[19])
+		implLen := int(binary.LittleEndian.Uint16(fid[36:38]))
+		length := 38 + implLen + nameLen
+		if len(fid) < length {
+			return nil, errors.New("Invalid UDF file identifier descriptor")
+		}
+		pos += (length + 3) &^ 3
+
+		if characteristics&(fileCharacteristicDel|fileCharacteristicParent) != 0 {
+			continue
+		}
+		name := decodeDString(fid[38+implLen : length])
+		block, partition := longAD(fid[20:36])
+		e, err := fs.readFileEntry(name, characteristics&fileCharacteristicDir != 0, block, partition)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// readFileEntry reads the extents of a file from its file entry or extended
+// file entry
+func (fs *udf) readFileEntry(name string, dir bool, block uint32, partition uint16) (entry, error) {
+	fe, err := fs.readBlockTag(block, partition)
+	if err != nil {
+		return entry{}, err
+	}
+	var eaLength, adLength, adStart int
+	switch tagID(fe) {
+	case tagFileEntry:
+		eaLength = int(binary.LittleEndian.Uint32(fe[168:172]))
+		adLength = int(binary.LittleEndian.Uint32(fe[172:176]))
+		adStart = 176 + eaLength
+	case tagExtendedFileEntry:
+		eaLength = int(binary.LittleEndian.Uint32(fe[208:212]))
+		adLength = int(binary.LittleEndian.Uint32(fe[212:216]))
+		adStart = 216 + eaLength
+	default:
+		return entry{}, fmt.Errorf("Invalid UDF file entry for %s", name)
+	}
+	if adStart+adLength > len(fe) {
+		return entry{}, fmt.Errorf("Invalid UDF allocation descriptors for %s", name)
+	}
+	e := entry{
+		name: name,
+		dir:  dir,
+		size: int64(binary.LittleEndian.Uint64(fe[56:64])),
+	}
+	ads := fe[adStart : adStart+adLength]
+	blockOffset := fs.blockOffset(block, partition)
+
+	switch binary.LittleEndian.Uint16(fe[34:36]) & 0x07 {
+	case embeddedAllocation:
+		// the content is stored in the file entry itself
+		e.extents = []extent{{offset: blockOffset + int64(adStart), length: int64(adLength)}}
+	case shortAllocation:
+		for pos := 0; pos+8 <= len(ads); pos += 8 {
+			ext, err := fs.allocationExtent(ads[pos:pos+8], binary.LittleEndian.Uint32(ads[pos+4:pos+8]), partition)
+			if err != nil {
+				return entry{}, err
+			}
+			e.extents = append(e.extents, ext)
+		}
+	case longAllocation:
+		for pos := 0; pos+16 <= len(ads); pos += 16 {
+			b, p := longAD(ads[pos : pos+16])
+			ext, err := fs.allocationExtent(ads[pos:pos+16], b, p)
+			if err != nil {
+				return entry{}, err
+			}
+			e.extents = append(e.extents, ext)
+		}
+	default:
+		return entry{}, fmt.Errorf("Unsupported UDF allocation descriptors for %s", name)
+	}
+	return e, nil
+}
+
+// allocationExtent converts a short or long allocation descriptor to an
+// extent in the image
+func (fs *udf) allocationExtent(ad []byte, block uint32, partition uint16) (extent, error) {
+	raw := binary.LittleEndian.Uint32(ad[0:4])
+	length := int64(raw & 0x3fffffff)
+	switch raw >> 30 {
+	case 0:
+		return extent{offset: fs.blockOffset(block, partition), length: length}, nil
+	case 1, 2:
+		return extent{offset: -1, length: length}, nil
+	}
+	return extent{}, errors.New("Unsupported UDF allocation extent continuation")
+}
+
+func (fs *udf) blockOffset(block uint32, partition uint16) int64 {
+	start := int64(0)
+	if int(partition) < len(fs.partitions) {
+		start = fs.partitions[partition]
+	}
+	return (start + int64(block)) * fs.blockSize
+}
+
+func (fs *udf) readBlockTag(block uint32, partition uint16) ([]byte, error) {
+	if int(partition) >= len(fs.partitions) {
+		return nil, fmt.Errorf("Invalid UDF partition reference %d", partition)
+	}
+	return readTag(fs.r, fs.blockOffset(block, partition), fs.blockSize)
+}
+
+// readTag reads a descriptor, verifying the tag checksum
+func readTag(r io.ReaderAt, offset, size int64) ([]byte, error) {
+	d := make([]byte, size)
+	if _, err := r.ReadAt(d, offset); err != nil && err != io.EOF {
+		return nil, err
+	}
+	var sum byte
+	for i := 0; i < 16; i++ {
+		if i != 4 {
+			sum += d[i]
+		}
+	}
+	if sum != d[4] {
+		return nil, errors.New("Invalid UDF descriptor tag checksum")
+	}
+	return d, nil
+}
+
+func tagID(d []byte) uint16 {
+	return binary.LittleEndian.Uint16(d[0:2])
+}
+
+// longAD returns the logical block and partition reference of a long
+// allocation descriptor
+func longAD(ad []byte) (uint32, uint16) {
+	return binary.LittleEndian.Uint32(ad[4:8]), binary.LittleEndian.Uint16(ad[8:10])
+}
+
+// decodeDString decodes an OSTA compressed unicode name, which has 8 or 16
+// bits per character
+func decodeDString(raw []byte) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	switch raw[0] {
+	case 8:
+		u := make([]uint16, len(raw)-1)
+		for i, b := range raw[1:] {
+			u[i] = uint16(b)
+		}
+		return string(utf16.Decode(u))
+	case 16:
+		u := make([]uint16, (len(raw)-1)/2)
+		for i := range u {
+			u[i] = binary.BigEndian.Uint16(raw[1+i*2:])
+		}
+		return string(utf16.Decode(u))
+	}
+	return string(raw[1:])
+}
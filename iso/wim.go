@@ -0,0 +1,169 @@
+package iso
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+)
+
+// wimMagic starts the header of WIM and ESD files
+var wimMagic = []byte("MSWIM\x00\x00\x00")
+
+// installImages are the paths of the Windows images on install media
+var installImages = []string{"sources/install.wim", "sources/install.esd"}
+
+// maxXMLSize limits the WIM XML metadata read into memory
+const maxXMLSize = 16 << 20
+
+// WIMImage describes a Windows image in a WIM or ESD file
+type WIMImage struct {
+	Index            int
+	Name             string
+	Description      string
+	EditionID        string
+	InstallationType string
+	Architecture     string
+	Languages        []string
+	DefaultLanguage  string
+	Version          string
+}
+
+// EditionName is a short lower case name for the image, suitable as the
+// edition name of an OS, e.g. professional or standardcore
+func (img WIMImage) EditionName() string {
+	name := img.EditionID
+	if len(name) == 0 {
+		name = img.Name
+	}
+	name = strings.ToLower(nonAlphanumeric.ReplaceAllString(name, ""))
+	if strings.HasPrefix(name, "server") && len(name) > len("server") {
+		name = strings.TrimPrefix(name, "server")
+	}
+	if strings.Contains(strings.ToLower(img.InstallationType), "core") && !strings.HasSuffix(name, "core") {
+		name += "core"
+	}
+	return name
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// architectures maps the WIM processor architecture numbers to names
+var architectures = map[int]string{
+	0:  "x86",
+	5:  "arm",
+	6:  "ia64",
+	9:  "amd64",
+	12: "arm64",
+}
+
+// InstallImages opens the ISO image and reads the Windows images of its
+// sources/install.wim or sources/install.esd
+func InstallImages(r io.ReaderAt) ([]WIMImage, error) {
+	fs, err := OpenImage(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range installImages {
+		wim, err := fs.Open(name)
+		if err == nil {
+			return WIMImages(wim)
+		}
+	}
+	return nil, fmt.Errorf("Couldn't find %s in the image", strings.Join(installImages, " or "))
+}
+
+// WIMImages reads the images described by the XML metadata of a WIM or ESD
+func WIMImages(r io.ReaderAt) ([]WIMImage, error) {
+	header := make([]byte, 208)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("Couldn't read the WIM header: %s", err)
+	}
+	if !bytes.Equal(header[:8], wimMagic) {
+		return nil, errors.New("Not a WIM or ESD file")
+	}
+
+	// the XML resource header, the size is 7 bytes followed by flags
+	xmlSize := int64(binary.LittleEndian.Uint64(header[72:80]) & 0x00ffffffffffffff)
+	xmlOffset := int64(binary.LittleEndian.Uint64(header[80:88]))
+	if xmlSize <= 0 || xmlSize > maxXMLSize {
+		return nil, fmt.Errorf("Invalid WIM XML metadata size %d", xmlSize)
+	}
+	raw := make([]byte, xmlSize)
+	if _, err := r.ReadAt(raw, xmlOffset); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("Couldn't read the WIM XML metadata: %s", err)
+	}
+	return parseWIMXML(raw)
+}
+
+type wimXML struct {
+	Images []struct {
+		Index       int    `xml:"INDEX,attr"`
+		Name        string `xml:"NAME"`
+		Description string `xml:"DESCRIPTION"`
+		Windows     struct {
+			Arch             int    `xml:"ARCH"`
+			EditionID        string `xml:"EDITIONID"`
+			InstallationType string `xml:"INSTALLATIONTYPE"`
+			Languages        struct {
+				Languages []string `xml:"LANGUAGE"`
+				Default   string   `xml:"DEFAULT"`
+			} `xml:"LANGUAGES"`
+			Version struct {
+				Major   string `xml:"MAJOR"`
+				Minor   string `xml:"MINOR"`
+				Build   string `xml:"BUILD"`
+				SPBuild string `xml:"SPBUILD"`
+			} `xml:"VERSION"`
+		} `xml:"WINDOWS"`
+	} `xml:"IMAGE"`
+}
+
+// parseWIMXML parses the UTF-16LE XML metadata
+func parseWIMXML(raw []byte) ([]WIMImage, error) {
+	u := make([]uint16, len(raw)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(raw[i*2:])
+	}
+	src := strings.TrimPrefix(string(utf16.Decode(u)), "\ufeff")
+
+	var doc wimXML
+	dec := xml.NewDecoder(strings.NewReader(src))
+	// the source has already been decoded from UTF-16
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("Couldn't parse the WIM XML metadata: %s", err)
+	}
+
+	images := make([]WIMImage, len(doc.Images))
+	for i, img := range doc.Images {
+		w := img.Windows
+		arch, ok := architectures[w.Arch]
+		if !ok {
+			arch = fmt.Sprintf("unknown (%d)", w.Arch)
+		}
+		version := ""
+		if len(w.Version.Major) > 0 {
+			version = strings.Join([]string{w.Version.Major, w.Version.Minor, w.Version.Build, w.Version.SPBuild}, ".")
+		}
+		images[i] = WIMImage{
+			Index:            img.Index,
+			Name:             img.Name,
+			Description:      img.Description,
+			EditionID:        w.EditionID,
+			InstallationType: w.InstallationType,
+			Architecture:     arch,
+			Languages:        w.Languages.Languages,
+			DefaultLanguage:  w.Languages.Default,
+			Version:          version,
+		}
+	}
+	return images, nil
+}
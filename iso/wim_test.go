@@ -0,0 +1,65 @@
+package iso_test
+
+import (
+	"bytes"
+
+	"github.com/joefitzgerald/inductor/iso"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WIM", func() {
+	It("should read the images of an install.wim", func() {
+		images, err := iso.InstallImages(fixture("udf.iso.gz"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(images).To(Equal([]iso.WIMImage{
+			{
+				Index:            1,
+				Name:             "Windows 10 Education",
+				Description:      "Windows 10 Education",
+				EditionID:        "Education",
+				InstallationType: "Client",
+				Architecture:     "amd64",
+				Languages:        []string{"en-US"},
+				DefaultLanguage:  "en-US",
+				Version:          "10.0.19041.1",
+			},
+			{
+				Index:            2,
+				Name:             "Windows 10 Pro",
+				Description:      "Windows 10 Pro",
+				EditionID:        "Professional",
+				InstallationType: "Client",
+				Architecture:     "amd64",
+				Languages:        []string{"en-US", "en-GB"},
+				DefaultLanguage:  "en-US",
+				Version:          "10.0.19041.1",
+			},
+		}))
+	})
+	It("should read the images of an install.esd", func() {
+		images, err := iso.InstallImages(fixture("joliet.iso.gz"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(images).To(HaveLen(2))
+		Expect(images[0].Name).To(Equal("Windows Server 2016 SERVERSTANDARDCORE"))
+		Expect(images[0].InstallationType).To(Equal("Server Core"))
+	})
+	It("should error for other files", func() {
+		_, err := iso.WIMImages(bytes.NewReader(make([]byte, 512)))
+		Expect(err).To(MatchError("Not a WIM or ESD file"))
+	})
+
+	Describe("EditionName", func() {
+		It("should use the edition ID", func() {
+			Expect(iso.WIMImage{EditionID: "Professional", InstallationType: "Client"}.EditionName()).To(Equal("professional"))
+		})
+		It("should distinguish server core installations", func() {
+			Expect(iso.WIMImage{EditionID: "ServerStandard", InstallationType: "Server Core"}.EditionName()).To(Equal("standardcore"))
+			Expect(iso.WIMImage{EditionID: "ServerDatacenter", InstallationType: "Server"}.EditionName()).To(Equal("datacenter"))
+		})
+		It("should fall back to the image name", func() {
+			Expect(iso.WIMImage{Name: "Windows 7 Ultimate"}.EditionName()).To(Equal("windows7ultimate"))
+		})
+	})
+})
@@ -0,0 +1,436 @@
+package iso
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// DefaultCDName is the file name of the rendered CD image
+const DefaultCDName = "unattend.iso"
+
+// The directory records and path tables of the ISO9660 file system and the
+// Joliet file system, which share the file data
+const (
+	primaryView = iota
+	jolietView
+	viewCount
+)
+
+const (
+	systemAreaSectors = 16
+	maxJolietName     = 64
+)
+
+// recordDate is 1980-01-01, the same as the floppy, so images are reproducible
+var recordDate = []byte{80, 1, 1, 0, 0, 0, 0}
+
+const (
+	volumeDate   = "1980010100000000"
+	volumeNoDate = "0000000000000000"
+)
+
+// File is a file to put on the CD, its slash separated name may include
+// directories
+type File struct {
+	Name    string
+	Content []byte
+}
+
+// node is a file or directory of the CD
+type node struct {
+	name     string
+	dir      bool
+	content  []byte
+	parent   *node
+	children []*node
+	// the identifier, extent and size in each view, files share their extent
+	ident  [viewCount][]byte
+	extent [viewCount]int
+	size   [viewCount]int
+	// the directory number in the view's path table
+	number [viewCount]int
+}
+
+// Write writes an ISO9660 image with Joliet names containing the files to w.
+// The Joliet names keep the case and length of the file names, which Windows
+// uses to find e.g. Autounattend.xml, and the ISO9660 names are 8.3 names for
+// other systems. An error is returned when two files have the same name or a
+// name is too long for Joliet.
+func Write(w io.Writer, label string, files []File) error {
+	root, err := newTree(files)
+	if err != nil {
+		return err
+	}
+	if err := nameTree(root); err != nil {
+		return err
+	}
+
+	var dirs [viewCount][]*node
+	var pathTableSize [viewCount]int
+	for v := range dirs {
+		dirs[v] = pathTableOrder(root, v)
+		for _, d := range dirs[v] {
+			pathTableSize[v] += pathTableEntryLen(d, v)
+		}
+	}
+
+	// the system area, the primary and Joliet volume descriptors and the
+	// terminator are followed by the little and big endian path tables
+	sector := systemAreaSectors + viewCount + 1
+	var pathTables [viewCount][2]int
+	for v := range pathTables {
+		for t := range pathTables[v] {
+			pathTables[v][t] = sector
+			sector += sectors(pathTableSize[v])
+		}
+	}
+	for v := range dirs {
+		for _, d := range dirs[v] {
+			d.extent[v] = sector
+			d.size[v] = dirSize(d, v)
+			sector += sectors(d.size[v])
+		}
+	}
+	fileNodes := allFiles(root)
+	for _, f := range fileNodes {
+		for v := range f.extent {
+			if len(f.content) > 0 {
+				f.extent[v] = sector
+			}
+			f.size[v] = len(f.content)
+		}
+		sector += sectors(len(f.content))
+	}
+
+	img := make([]byte, sector*sectorSize)
+	for v := range dirs {
+		vd := img[(systemAreaSectors+v)*sectorSize:]
+		writeVolumeDescriptor(vd, v, label, sector, pathTableSize[v], pathTables[v], root)
+		writePathTable(img[pathTables[v][0]*sectorSize:], dirs[v], v, binary.LittleEndian)
+		writePathTable(img[pathTables[v][1]*sectorSize:], dirs[v], v, binary.BigEndian)
+		for _, d := range dirs[v] {
+			writeDir(img[d.extent[v]*sectorSize:], d, v)
+		}
+	}
+	terminator := img[(systemAreaSectors+viewCount)*sectorSize:]
+	terminator[0] = volumeDescriptorTerminator
+	copy(terminator[1:6], isoIdentifier)
+	terminator[6] = 1
+	for _, f := range fileNodes {
+		copy(img[f.extent[primaryView]*sectorSize:], f.content)
+	}
+	_, err = w.Write(img)
+	return err
+}
+
+// newTree creates the directories of the slash separated file names
+func newTree(files []File) (*node, error) {
+	root := &node{dir: true}
+	root.parent = root
+	for _, f := range files {
+		parts := strings.Split(strings.Trim(f.Name, "/"), "/")
+		dir := root
+		for i, part := range parts {
+			last := i == len(parts)-1
+			child := dir.child(part)
+			if child != nil && (last || !child.dir) {
+				return nil, fmt.Errorf("More than one CD file is named %s", strings.Join(parts[:i+1], "/"))
+			}
+			if child == nil {
+				child = &node{name: part, dir: !last, parent: dir}
+				if last {
+					child.content = f.Content
+				}
+				dir.children = append(dir.children, child)
+			}
+			dir = child
+		}
+	}
+	return root, nil
+}
+
+// child finds the file or directory in the directory, ignoring case
+func (n *node) child(name string) *node {
+	for _, c := range n.children {
+		if strings.EqualFold(c.name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// nameTree creates the unique ISO9660 and Joliet identifiers of every file
+// and directory
+func nameTree(dir *node) error {
+	used := make(map[string]bool)
+	for _, c := range dir.children {
+		c.ident[primaryView] = primaryIdent(c, used)
+		if len(utf16.Encode([]rune(c.name))) > maxJolietName {
+			return fmt.Errorf("The CD file name %s is longer than the %d characters a CD allows", c.name, maxJolietName)
+		}
+		c.ident[jolietView] = jolietText(c.name, maxJolietName)
+		if c.dir {
+			if err := nameTree(c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// primaryIdent creates a unique 8.3 ISO9660 identifier, e.g. AUTOUNAT.XML;1
+// for Autounattend.xml
+func primaryIdent(n *node, used map[string]bool) []byte {
+	base, ext := n.name, ""
+	if i := strings.LastIndex(n.name, "."); i > 0 && !n.dir {
+		base, ext = n.name[:i], n.name[i+1:]
+	}
+	base, ext = dChars(base), dChars(ext)
+	if len(ext) > 3 {
+		ext = ext[:3]
+	}
+	if len(base) == 0 {
+		base = "_"
+	}
+	for i := 0; ; i++ {
+		suffix := ""
+		if i > 0 {
+			suffix = fmt.Sprintf("_%d", i)
+		}
+		b := base
+		if len(b) > 8-len(suffix) {
+			b = b[:8-len(suffix)]
+		}
+		ident := b + suffix
+		if !n.dir {
+			ident += "." + ext + ";1"
+		}
+		if !used[ident] {
+			used[ident] = true
+			return []byte(ident)
+		}
+	}
+}
+
+// dChars upper cases the name, replacing the characters ISO9660 names can't
+// have with an underscore
+func dChars(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// byIdent sorts the files and directories of a directory by their identifier
+// in a view
+type byIdent struct {
+	nodes []*node
+	view  int
+}
+
+func (s byIdent) Len() int      { return len(s.nodes) }
+func (s byIdent) Swap(i, j int) { s.nodes[i], s.nodes[j] = s.nodes[j], s.nodes[i] }
+func (s byIdent) Less(i, j int) bool {
+	return bytes.Compare(s.nodes[i].ident[s.view], s.nodes[j].ident[s.view]) < 0
+}
+
+func sortedChildren(dir *node, v int) []*node {
+	children := append([]*node{}, dir.children...)
+	sort.Sort(byIdent{children, v})
+	return children
+}
+
+// pathTableOrder lists the directories level by level, which is the order of
+// the path table, numbering them as it goes
+func pathTableOrder(root *node, v int) []*node {
+	dirs := []*node{root}
+	for i := 0; i < len(dirs); i++ {
+		dirs[i].number[v] = i + 1
+		for _, c := range sortedChildren(dirs[i], v) {
+			if c.dir {
+				dirs = append(dirs, c)
+			}
+		}
+	}
+	return dirs
+}
+
+// allFiles lists the files in the order of the ISO9660 directories
+func allFiles(dir *node) []*node {
+	files := []*node{}
+	for _, c := range sortedChildren(dir, primaryView) {
+		if c.dir {
+			files = append(files, allFiles(c)...)
+		} else {
+			files = append(files, c)
+		}
+	}
+	return files
+}
+
+func sectors(size int) int {
+	return (size + sectorSize - 1) / sectorSize
+}
+
+func pathTableIdent(d *node, v int) []byte {
+	if d.parent == d {
+		return []byte{0}
+	}
+	return d.ident[v]
+}
+
+func pathTableEntryLen(d *node, v int) int {
+	n := len(pathTableIdent(d, v))
+	return 8 + n + n%2
+}
+
+func writePathTable(b []byte, dirs []*node, v int, order binary.ByteOrder) {
+	pos := 0
+	for _, d := range dirs {
+		ident := pathTableIdent(d, v)
+		b[pos] = byte(len(ident))
+		order.PutUint32(b[pos+2:], uint32(d.extent[v]))
+		order.PutUint16(b[pos+6:], uint16(d.parent.number[v]))
+		copy(b[pos+8:], ident)
+		pos += pathTableEntryLen(d, v)
+	}
+}
+
+// dirRecord is a record of a directory, including its . and .. records
+type dirRecord struct {
+	node  *node
+	ident []byte
+}
+
+func dirRecords(d *node, v int) []dirRecord {
+	records := []dirRecord{{d, []byte{0}}, {d.parent, []byte{1}}}
+	for _, c := range sortedChildren(d, v) {
+		records = append(records, dirRecord{c, c.ident[v]})
+	}
+	return records
+}
+
+func recordLen(ident []byte) int {
+	n := 33 + len(ident)
+	return n + n%2
+}
+
+// dirSize is the size of the directory's records, which don't span sectors
+func dirSize(d *node, v int) int {
+	pos := 0
+	for _, r := range dirRecords(d, v) {
+		n := recordLen(r.ident)
+		if pos%sectorSize+n > sectorSize {
+			pos = sectors(pos) * sectorSize
+		}
+		pos += n
+	}
+	return sectors(pos) * sectorSize
+}
+
+func writeDir(b []byte, d *node, v int) {
+	pos := 0
+	for _, r := range dirRecords(d, v) {
+		n := recordLen(r.ident)
+		if pos%sectorSize+n > sectorSize {
+			pos = sectors(pos) * sectorSize
+		}
+		writeRecord(b[pos:], r.node, v, r.ident)
+		pos += n
+	}
+}
+
+func writeRecord(b []byte, n *node, v int, ident []byte) {
+	b[0] = byte(recordLen(ident))
+	putBoth32(b[2:], uint32(n.extent[v]))
+	putBoth32(b[10:], uint32(n.size[v]))
+	copy(b[18:25], recordDate)
+	if n.dir {
+		b[25] = recordDirectory
+	}
+	putBoth16(b[28:], 1)
+	b[32] = byte(len(ident))
+	copy(b[33:], ident)
+}
+
+func writeVolumeDescriptor(b []byte, v int, label string, volumeSize, pathTableSize int, pathTables [2]int, root *node) {
+	b[0] = primaryVolumeDescriptor
+	if v == jolietView {
+		b[0] = supplementaryVolumeDescriptor
+	}
+	copy(b[1:6], isoIdentifier)
+	b[6] = 1
+	fillText(b[8:72], v)
+	fillText(b[190:813], v)
+	if v == jolietView {
+		// the Joliet UCS-2 level 3 escape sequence
+		copy(b[88:91], "%/E")
+		copy(b[40:72], jolietText(label, 16))
+	} else {
+		copy(b[40:72], dChars(truncate(label, 32)))
+	}
+	putBoth32(b[80:], uint32(volumeSize))
+	putBoth16(b[120:], 1)
+	putBoth16(b[124:], 1)
+	putBoth16(b[128:], sectorSize)
+	putBoth32(b[132:], uint32(pathTableSize))
+	binary.LittleEndian.PutUint32(b[140:], uint32(pathTables[0]))
+	binary.BigEndian.PutUint32(b[148:], uint32(pathTables[1]))
+	writeRecord(b[156:190], root, v, []byte{0})
+	copy(b[813:], volumeDate)
+	copy(b[830:], volumeDate)
+	copy(b[847:], volumeNoDate)
+	copy(b[864:], volumeNoDate)
+	b[881] = 1
+}
+
+// fillText pads a text field with spaces, which are UCS-2 in Joliet
+func fillText(b []byte, v int) {
+	for i := range b {
+		b[i] = ' '
+		if v == jolietView && i%2 == 0 {
+			b[i] = 0
+		}
+	}
+}
+
+// jolietText encodes the text as big endian UCS-2, truncated to max characters
+func jolietText(s string, max int) []byte {
+	units := utf16.Encode([]rune(s))
+	if len(units) > max {
+		units = units[:max]
+	}
+	b := make([]byte, len(units)*2)
+	for i, u := range units {
+		binary.BigEndian.PutUint16(b[i*2:], u)
+	}
+	return b
+}
+
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
+
+// putBoth32 writes a both-byte orders value, little endian then big endian
+func putBoth32(b []byte, v uint32) {
+	binary.LittleEndian.PutUint32(b[0:4], v)
+	binary.BigEndian.PutUint32(b[4:8], v)
+}
+
+func putBoth16(b []byte, v uint16) {
+	binary.LittleEndian.PutUint16(b[0:2], v)
+	binary.BigEndian.PutUint16(b[2:4], v)
+}
@@ -0,0 +1,101 @@
+package iso_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/joefitzgerald/inductor/iso"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Write", func() {
+	var (
+		err   error
+		img   bytes.Buffer
+		files []iso.File
+	)
+
+	BeforeEach(func() {
+		img.Reset()
+		files = []iso.File{
+			{Name: "Autounattend.xml", Content: []byte("<unattend/>")},
+			{Name: "scripts/enable-winrm.ps1", Content: []byte("Enable-PSRemoting -Force")},
+			{Name: "scripts/tools/empty.cmd", Content: []byte{}},
+		}
+	})
+
+	readFile := func(name string) []byte {
+		fs, err := iso.OpenImage(bytes.NewReader(img.Bytes()))
+		Expect(err).NotTo(HaveOccurred())
+		r, err := fs.Open(name)
+		Expect(err).NotTo(HaveOccurred())
+		content, err := ioutil.ReadAll(r)
+		Expect(err).NotTo(HaveOccurred())
+		return content
+	}
+
+	It("should write the files with their Joliet names", func() {
+		err = iso.Write(&img, "windows2012r2", files)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(img.Len() % 2048).To(Equal(0))
+		Expect(readFile("Autounattend.xml")).To(Equal([]byte("<unattend/>")))
+		Expect(readFile("scripts/enable-winrm.ps1")).To(Equal([]byte("Enable-PSRemoting -Force")))
+		Expect(readFile("scripts/tools/empty.cmd")).To(BeEmpty())
+	})
+
+	It("should write 8.3 ISO9660 names and the volume label", func() {
+		err = iso.Write(&img, "windows2012r2", files)
+		Expect(err).NotTo(HaveOccurred())
+		primary := img.Bytes()[16*2048 : 17*2048]
+		Expect(primary[0]).To(Equal(byte(1)))
+		Expect(string(primary[1:6])).To(Equal("CD001"))
+		Expect(string(primary[40:72])).To(Equal(fmt.Sprintf("%-32s", "WINDOWS2012R2")))
+		Expect(img.Bytes()).To(ContainSubstring("AUTOUNAT.XML;1"))
+		Expect(img.Bytes()).To(ContainSubstring("ENABLE_W.PS1;1"))
+	})
+
+	It("should be reproducible", func() {
+		err = iso.Write(&img, "windows2012r2", files)
+		Expect(err).NotTo(HaveOccurred())
+		var again bytes.Buffer
+		err = iso.Write(&again, "windows2012r2", files)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again.Bytes()).To(Equal(img.Bytes()))
+	})
+
+	It("should write directories spanning more than one sector", func() {
+		files = nil
+		for i := 0; i < 100; i++ {
+			name := fmt.Sprintf("scripts/%s-%d.ps1", strings.Repeat("long-script-name", 2), i)
+			files = append(files, iso.File{Name: name, Content: []byte(name)})
+		}
+		err = iso.Write(&img, "windows2012r2", files)
+		Expect(err).NotTo(HaveOccurred())
+		for _, f := range files {
+			Expect(readFile(f.Name)).To(Equal(f.Content))
+		}
+	})
+
+	It("should not write two files with the same name", func() {
+		files = append(files, iso.File{Name: "AUTOUNATTEND.XML"})
+		err = iso.Write(&img, "windows2012r2", files)
+		Expect(err).To(MatchError("More than one CD file is named AUTOUNATTEND.XML"))
+	})
+
+	It("should not write a file with the name of a directory", func() {
+		files = append(files, iso.File{Name: "scripts"})
+		err = iso.Write(&img, "windows2012r2", files)
+		Expect(err).To(MatchError("More than one CD file is named scripts"))
+	})
+
+	It("should not write names longer than Joliet allows", func() {
+		name := strings.Repeat("a", 61) + ".xml"
+		files = append(files, iso.File{Name: name})
+		err = iso.Write(&img, "windows2012r2", files)
+		Expect(err).To(MatchError("The CD file name " + name + " is longer than the 64 characters a CD allows"))
+	})
+})
@@ -0,0 +1,82 @@
+package output
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"time"
+)
+
+type tarArchive struct {
+	tw *tar.Writer
+	gz *gzip.Writer
+}
+
+// NewTar creates an Archive which streams a tar archive to the writer
+func NewTar(w io.Writer) Archive {
+	return &tarArchive{tw: tar.NewWriter(w)}
+}
+
+// NewTarGz creates an Archive which streams a gzipped tar archive to the writer
+func NewTarGz(w io.Writer) Archive {
+	gz := gzip.NewWriter(w)
+	return &tarArchive{tw: tar.NewWriter(gz), gz: gz}
+}
+
+func (a *tarArchive) Write(name string, content io.Reader) error {
+	// tar headers need the size up front
+	var buffer bytes.Buffer
+	if _, err := buffer.ReadFrom(content); err != nil {
+		return err
+	}
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(buffer.Len()),
+		ModTime: time.Now(),
+	}
+	if err := a.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := buffer.WriteTo(a.tw)
+	return err
+}
+
+func (a *tarArchive) Close() error {
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+	if a.gz != nil {
+		return a.gz.Close()
+	}
+	return nil
+}
+
+type zipArchive struct {
+	zw *zip.Writer
+}
+
+// NewZip creates an Archive which streams a zip archive to the writer
+func NewZip(w io.Writer) Archive {
+	return &zipArchive{zw: zip.NewWriter(w)}
+}
+
+func (a *zipArchive) Write(name string, content io.Reader) error {
+	hdr := &zip.FileHeader{
+		Name:   name,
+		Method: zip.Deflate,
+	}
+	hdr.SetModTime(time.Now())
+	f, err := a.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, content)
+	return err
+}
+
+func (a *zipArchive) Close() error {
+	return a.zw.Close()
+}
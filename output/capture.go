@@ -0,0 +1,55 @@
+package output
+
+import (
+	"bytes"
+	"io"
+	"path"
+)
+
+// Capture is an Output which writes every file to its output and keeps a copy
+// of the files matching any of its patterns, e.g. to pack them into a disk
+// image once everything has been written
+type Capture struct {
+	out      Output
+	patterns []string
+	captured *Memory
+}
+
+// NewCapture creates an Output capturing the files whose slash separated name
+// matches any of the path.Match patterns
+func NewCapture(out Output, patterns []string) *Capture {
+	return &Capture{
+		out:      out,
+		patterns: patterns,
+		captured: NewMemory(),
+	}
+}
+
+// Write writes the file to the output, keeping a copy if it matches
+func (c *Capture) Write(name string, content io.Reader) error {
+	if !c.matches(name) {
+		return c.out.Write(name, content)
+	}
+	var buffer bytes.Buffer
+	if _, err := buffer.ReadFrom(content); err != nil {
+		return err
+	}
+	if err := c.captured.Write(name, bytes.NewReader(buffer.Bytes())); err != nil {
+		return err
+	}
+	return c.out.Write(name, &buffer)
+}
+
+// Captured returns the files which matched a pattern
+func (c *Capture) Captured() *Memory {
+	return c.captured
+}
+
+func (c *Capture) matches(name string) bool {
+	for _, pattern := range c.patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
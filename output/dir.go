@@ -0,0 +1,36 @@
+package output
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+type dir struct {
+	path string
+}
+
+// NewDir creates an Output which writes to the given directory on disk
+func NewDir(path string) Output {
+	return &dir{path: path}
+}
+
+func (d *dir) Write(name string, content io.Reader) (err error) {
+	target := filepath.Join(d.path, filepath.FromSlash(name))
+	if err = os.MkdirAll(filepath.Dir(target), 0777); err != nil {
+		return err
+	}
+	f, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+	if _, err = io.Copy(f, content); err != nil {
+		return err
+	}
+	return f.Sync()
+}
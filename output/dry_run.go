@@ -0,0 +1,83 @@
+package output
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Status describes how a file would change the output directory
+type Status string
+
+// All the possible file statuses
+const (
+	New       Status = "new"
+	Changed   Status = "changed"
+	Unchanged Status = "unchanged"
+)
+
+// File is a single file which would have been written
+type File struct {
+	Name   string
+	Size   int64
+	Status Status
+}
+
+// DryRun is an Output which records every file that would be written to a
+// directory, comparing it to the existing file without touching the disk
+type DryRun struct {
+	path  string
+	Files []File
+}
+
+// NewDryRun creates a DryRun for the given directory
+func NewDryRun(path string) *DryRun {
+	return &DryRun{path: path}
+}
+
+// Write records the file and whether it is new, changed or unchanged
+func (d *DryRun) Write(name string, content io.Reader) error {
+	h := sha256.New()
+	size, err := io.Copy(h, content)
+	if err != nil {
+		return err
+	}
+	status, err := d.status(filepath.Join(d.path, filepath.FromSlash(name)), size, h.Sum(nil))
+	if err != nil {
+		return err
+	}
+	d.Files = append(d.Files, File{Name: name, Size: size, Status: status})
+	return nil
+}
+
+func (d *DryRun) status(existing string, size int64, sum []byte) (s Status, err error) {
+	f, err := os.Open(existing)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return New, nil
+		}
+		return "", err
+	}
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if fi.IsDir() || fi.Size() != size {
+		return Changed, nil
+	}
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	if !bytes.Equal(h.Sum(nil), sum) {
+		return Changed, nil
+	}
+	return Unchanged, nil
+}
@@ -0,0 +1,41 @@
+package output
+
+import (
+	"bytes"
+	"io"
+)
+
+// Memory is an Output which captures every file in memory
+type Memory struct {
+	names []string
+	files map[string][]byte
+}
+
+// NewMemory creates an empty Memory output
+func NewMemory() *Memory {
+	return &Memory{files: make(map[string][]byte)}
+}
+
+// Write captures the content of the file
+func (m *Memory) Write(name string, content io.Reader) error {
+	var buffer bytes.Buffer
+	if _, err := buffer.ReadFrom(content); err != nil {
+		return err
+	}
+	if _, ok := m.files[name]; !ok {
+		m.names = append(m.names, name)
+	}
+	m.files[name] = buffer.Bytes()
+	return nil
+}
+
+// Names lists all captured files in the order they were first written
+func (m *Memory) Names() []string {
+	return m.names
+}
+
+// File returns the content of the named file if it was written
+func (m *Memory) File(name string) ([]byte, bool) {
+	content, ok := m.files[name]
+	return content, ok
+}
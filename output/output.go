@@ -0,0 +1,16 @@
+package output
+
+import "io"
+
+// Output is the destination of all rendered templates and copied files
+type Output interface {
+	// Write stores the content under the slash separated name, which is
+	// relative to the root of the output
+	Write(name string, content io.Reader) error
+}
+
+// Archive is an Output which must be closed to finish writing the archive
+type Archive interface {
+	Output
+	Close() error
+}
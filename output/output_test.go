@@ -0,0 +1,210 @@
+package output_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joefitzgerald/inductor/output"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Output", func() {
+	var (
+		err    error
+		outDir string
+	)
+
+	BeforeEach(func() {
+		outDir, err = ioutil.TempDir("", "inductor")
+		Expect(err).NotTo(HaveOccurred())
+	})
+	AfterEach(func() {
+		os.RemoveAll(outDir)
+	})
+
+	Describe("Dir", func() {
+		BeforeEach(func() {
+			err = output.NewDir(outDir).Write("scripts/nano/SetupComplete.cmd", strings.NewReader("setup"))
+		})
+		It("should not error", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("should write the file creating any parent dirs", func() {
+			bytes, err := ioutil.ReadFile(filepath.Join(outDir, "scripts", "nano", "SetupComplete.cmd"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(bytes)).To(Equal("setup"))
+		})
+	})
+
+	Describe("Capture", func() {
+		var (
+			memory  *output.Memory
+			capture *output.Capture
+		)
+		BeforeEach(func() {
+			memory = output.NewMemory()
+			capture = output.NewCapture(memory, []string{"Autounattend.xml", "scripts/*.ps1"})
+			for _, name := range []string{"packer.json", "Autounattend.xml", "scripts/winrm.ps1", "scripts/nano/setup.cmd"} {
+				Expect(capture.Write(name, strings.NewReader(name))).To(Succeed())
+			}
+		})
+		It("should write every file to the output", func() {
+			Expect(memory.Names()).To(Equal([]string{"packer.json", "Autounattend.xml", "scripts/winrm.ps1", "scripts/nano/setup.cmd"}))
+			content, _ := memory.File("scripts/winrm.ps1")
+			Expect(string(content)).To(Equal("scripts/winrm.ps1"))
+		})
+		It("should keep a copy of the matching files", func() {
+			Expect(capture.Captured().Names()).To(Equal([]string{"Autounattend.xml", "scripts/winrm.ps1"}))
+			content, _ := capture.Captured().File("Autounattend.xml")
+			Expect(string(content)).To(Equal("Autounattend.xml"))
+		})
+	})
+
+	Describe("Memory", func() {
+		var memory *output.Memory
+		BeforeEach(func() {
+			memory = output.NewMemory()
+			Expect(memory.Write("packer.json", strings.NewReader("{}"))).To(Succeed())
+			Expect(memory.Write("Vagrantfile", strings.NewReader("old"))).To(Succeed())
+			Expect(memory.Write("Vagrantfile", strings.NewReader("new"))).To(Succeed())
+		})
+		It("should list the files in write order", func() {
+			Expect(memory.Names()).To(Equal([]string{"packer.json", "Vagrantfile"}))
+		})
+		It("should keep the last content written", func() {
+			content, ok := memory.File("Vagrantfile")
+			Expect(ok).To(BeTrue())
+			Expect(string(content)).To(Equal("new"))
+		})
+		It("should not find files never written", func() {
+			_, ok := memory.File("Autounattend.xml")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("Archives", func() {
+		var buffer *bytes.Buffer
+		BeforeEach(func() {
+			buffer = new(bytes.Buffer)
+		})
+		writeFiles := func(out output.Output) {
+			Expect(out.Write("packer.json", strings.NewReader("{}"))).To(Succeed())
+			Expect(out.Write("scripts/winrm.ps1", strings.NewReader("winrm"))).To(Succeed())
+		}
+		readTar := func(r io.Reader) map[string]string {
+			files := make(map[string]string)
+			tr := tar.NewReader(r)
+			for {
+				hdr, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				Expect(err).NotTo(HaveOccurred())
+				content, err := ioutil.ReadAll(tr)
+				Expect(err).NotTo(HaveOccurred())
+				files[hdr.Name] = string(content)
+			}
+			return files
+		}
+		expected := map[string]string{
+			"packer.json":       "{}",
+			"scripts/winrm.ps1": "winrm",
+		}
+
+		It("should stream a tar archive", func() {
+			archive := output.NewTar(buffer)
+			writeFiles(archive)
+			Expect(archive.Close()).To(Succeed())
+			Expect(readTar(buffer)).To(Equal(expected))
+		})
+		It("should stream a gzipped tar archive", func() {
+			archive := output.NewTarGz(buffer)
+			writeFiles(archive)
+			Expect(archive.Close()).To(Succeed())
+			gz, err := gzip.NewReader(buffer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(readTar(gz)).To(Equal(expected))
+		})
+		It("should stream a zip archive", func() {
+			archive := output.NewZip(buffer)
+			writeFiles(archive)
+			Expect(archive.Close()).To(Succeed())
+			zr, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+			Expect(err).NotTo(HaveOccurred())
+			files := make(map[string]string)
+			for _, f := range zr.File {
+				rc, err := f.Open()
+				Expect(err).NotTo(HaveOccurred())
+				content, err := ioutil.ReadAll(rc)
+				Expect(err).NotTo(HaveOccurred())
+				rc.Close()
+				files[f.Name] = string(content)
+			}
+			Expect(files).To(Equal(expected))
+		})
+		It("should put files under a prefix", func() {
+			archive := output.NewTar(buffer)
+			writeFiles(output.WithPrefix(archive, "windows10/enterprise"))
+			Expect(archive.Close()).To(Succeed())
+			Expect(readTar(buffer)).To(HaveKey("windows10/enterprise/scripts/winrm.ps1"))
+		})
+	})
+
+	Describe("Text", func() {
+		It("should write every file with a header", func() {
+			var buffer bytes.Buffer
+			out := output.NewText(&buffer)
+			Expect(out.Write("Vagrantfile", strings.NewReader("vagrant"))).To(Succeed())
+			Expect(out.Write("packer.json", strings.NewReader("{}"))).To(Succeed())
+			Expect(buffer.String()).To(Equal("==> Vagrantfile <==\nvagrant\n==> packer.json <==\n{}\n"))
+		})
+	})
+
+	Describe("WithRedaction", func() {
+		It("should redact the content of every file", func() {
+			memory := output.NewMemory()
+			out := output.WithRedaction(memory, func(s string) string {
+				return strings.Replace(s, "hunter2", "********", -1)
+			})
+			Expect(out.Write("Autounattend.xml", strings.NewReader("<Password>hunter2</Password>"))).To(Succeed())
+			content, _ := memory.File("Autounattend.xml")
+			Expect(string(content)).To(Equal("<Password>********</Password>"))
+		})
+	})
+
+	Describe("DryRun", func() {
+		var dryRun *output.DryRun
+		BeforeEach(func() {
+			Expect(ioutil.WriteFile(filepath.Join(outDir, "Vagrantfile"), []byte("same"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(outDir, "packer.json"), []byte("old"), 0644)).To(Succeed())
+			dryRun = output.NewDryRun(outDir)
+			Expect(dryRun.Write("Vagrantfile", strings.NewReader("same"))).To(Succeed())
+			Expect(dryRun.Write("packer.json", strings.NewReader("new"))).To(Succeed())
+			Expect(dryRun.Write("scripts/winrm.ps1", strings.NewReader("winrm"))).To(Succeed())
+		})
+		It("should record every file with its size and status", func() {
+			Expect(dryRun.Files).To(Equal([]output.File{
+				{Name: "Vagrantfile", Size: 4, Status: output.Unchanged},
+				{Name: "packer.json", Size: 3, Status: output.Changed},
+				{Name: "scripts/winrm.ps1", Size: 5, Status: output.New},
+			}))
+		})
+		It("should not touch the existing files", func() {
+			bytes, err := ioutil.ReadFile(filepath.Join(outDir, "packer.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(bytes)).To(Equal("old"))
+		})
+		It("should not create new files", func() {
+			Expect(filepath.Join(outDir, "scripts")).NotTo(BeADirectory())
+		})
+	})
+})
@@ -0,0 +1,21 @@
+package output
+
+import (
+	"io"
+	"path"
+)
+
+type prefixed struct {
+	out    Output
+	prefix string
+}
+
+// WithPrefix creates an Output which writes every file to the given output
+// under the slash separated prefix, e.g. to put several renders in one archive
+func WithPrefix(out Output, prefix string) Output {
+	return &prefixed{out: out, prefix: prefix}
+}
+
+func (p *prefixed) Write(name string, content io.Reader) error {
+	return p.out.Write(path.Join(p.prefix, name), content)
+}
@@ -0,0 +1,27 @@
+package output
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+type redacted struct {
+	out    Output
+	redact func(string) string
+}
+
+// WithRedaction creates an Output which passes the content of every file
+// through the redact function before writing it to the given output, e.g. to
+// hide secrets from output meant to be read rather than used
+func WithRedaction(out Output, redact func(string) string) Output {
+	return &redacted{out: out, redact: redact}
+}
+
+func (r *redacted) Write(name string, content io.Reader) error {
+	b, err := ioutil.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	return r.out.Write(name, strings.NewReader(r.redact(string(b))))
+}
@@ -0,0 +1,27 @@
+package output
+
+import (
+	"fmt"
+	"io"
+)
+
+type text struct {
+	w io.Writer
+}
+
+// NewText creates an Output which writes every file to the writer, each
+// preceded by a header line with the file name
+func NewText(w io.Writer) Output {
+	return &text{w: w}
+}
+
+func (t *text) Write(name string, content io.Reader) error {
+	if _, err := fmt.Fprintf(t.w, "==> %s <==\n", name); err != nil {
+		return err
+	}
+	if _, err := io.Copy(t.w, content); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(t.w)
+	return err
+}
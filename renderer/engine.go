@@ -1,75 +1,156 @@
 package renderer
 
 import (
-	"bufio"
 	"bytes"
+	"fmt"
 	"io"
 	"os"
-	"path/filepath"
+	"path"
+	"strings"
 	"text/template"
 
+	"github.com/joefitzgerald/inductor/output"
 	"github.com/joefitzgerald/inductor/tpl"
 )
 
+// noValue is what text/template renders for missing values
+const noValue = "<no value>"
+
 type engine struct {
 	renderOptions *RenderOptions
-	outDir        string
+	out           output.Output
+	copies        []string
+}
+
+// New creates a new Renderer instance which writes to the given output
+func New(opts *RenderOptions, out output.Output) Renderer {
+	return &engine{
+		renderOptions: opts,
+		out:           out,
+	}
 }
 
-// New creates a new Renderer instance
-func New(opts *RenderOptions, outDir string) Renderer {
+// NewWithCopies creates a new Renderer instance which writes to the given
+// output alongside the copied files, the slash separated paths of the copies
+// relative to the output dir, so a template can't overwrite a copy
+func NewWithCopies(opts *RenderOptions, out output.Output, copies []string) Renderer {
 	return &engine{
 		renderOptions: opts,
-		outDir:        outDir,
+		out:           out,
+		copies:        copies,
 	}
 }
 
 // Render generates the packer.json and Autounattend.xml files used by Packer
 func (e *engine) Render(tc tpl.TemplateContainer) error {
-	if err := e.createOutputDir(); err != nil {
+	if e.renderOptions.Strict {
+		if err := e.renderOptions.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := e.renderOptions.CheckLocalISOs(os.Stderr); err != nil {
 		return err
 	}
-	for _, t := range tc.ListTemplates() {
-		if err := e.writeTemplate(t); err != nil {
+
+	templates := tc.ListTemplates()
+	if err := e.checkCollisions(templates); err != nil {
+		return err
+	}
+
+	// render everything before writing so a failing template doesn't leave
+	// behind a partially rendered output dir
+	rendered := make([][]byte, len(templates))
+	for i, t := range templates {
+		var buffer bytes.Buffer
+		if err := e.renderTemplate(t, &buffer); err != nil {
+			return err
+		}
+		rendered[i] = buffer.Bytes()
+	}
+
+	for i, t := range templates {
+		if err := e.out.Write(outputPath(t), bytes.NewReader(rendered[i])); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (e *engine) createOutputDir() error {
-	return os.MkdirAll(e.outDir, 0777)
+// checkCollisions ensures no two templates render to the same output file and
+// no template renders to a copied file, ignoring case as the output may end up
+// on a case insensitive file system
+func (e *engine) checkCollisions(templates []tpl.Templater) error {
+	copies := make(map[string]string)
+	for _, c := range e.copies {
+		copies[strings.ToLower(c)] = c
+	}
+	seen := make(map[string]tpl.Templater)
+	for _, t := range templates {
+		key := strings.ToLower(outputPath(t))
+		if other, ok := seen[key]; ok {
+			return fmt.Errorf("Templates %s and %s both render to %s", other.Name(), t.Name(), outputPath(t))
+		}
+		if c, ok := copies[key]; ok {
+			return fmt.Errorf("Template %s renders to %s which is also copied from %s", t.Name(), outputPath(t), c)
+		}
+		seen[key] = t
+	}
+	return nil
+}
+
+// outputPath is where the template renders to relative to the output dir,
+// keeping the template's directory relative to the base dir
+func outputPath(t tpl.Templater) string {
+	return path.Join(path.Dir(t.Name()), t.BaseFilename())
 }
 
-func (e *engine) writeTemplate(t tpl.Templater) (err error) {
-	f, err := os.Create(filepath.Join(e.outDir, t.BaseFilename()))
+func (e *engine) renderTemplate(t tpl.Templater, outWriter io.Writer) error {
+	tmpl, err := e.parseTemplate(t)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if cerr := f.Close(); cerr != nil {
-			err = cerr
-		}
-	}()
-	w := bufio.NewWriter(f)
-	defer func() {
-		if cerr := w.Flush(); cerr != nil {
-			err = cerr
-		}
-	}()
-	err = e.renderTemplate(t, w)
+	if !e.renderOptions.Strict {
+		return tmpl.Execute(outWriter, e.renderOptions)
+	}
+
+	var out bytes.Buffer
+	if err = tmpl.Execute(&out, e.renderOptions); err != nil {
+		return err
+	}
+	if i := bytes.Index(out.Bytes(), []byte(noValue)); i >= 0 {
+		line := bytes.Count(out.Bytes()[:i], []byte("\n")) + 1
+		return fmt.Errorf("%s:%d rendered %s, the template references a missing value", t.Name(), line, noValue)
+	}
+	_, err = out.WriteTo(outWriter)
 	return err
 }
 
-func (e *engine) renderTemplate(tpl tpl.Templater, outWriter io.Writer) error {
+// parseTemplate parses the root template and each of its partials under their
+// own file names, so any parse or exec errors point at the actual source file
+func (e *engine) parseTemplate(t tpl.Templater) (*template.Template, error) {
+	tmpl := template.New(t.Name()).Funcs(templateFuncs)
+	if e.renderOptions.Strict {
+		tmpl = tmpl.Option("missingkey=error")
+	}
+
 	var buffer bytes.Buffer
-	err := tpl.Content(&buffer)
-	if err != nil {
-		return err
+	if err := t.Source(&buffer); err != nil {
+		return nil, err
 	}
-	tmpl, err := template.New("tpl").Funcs(templateFuncs).Parse(buffer.String())
-	if err != nil {
-		return err
+	if _, err := tmpl.Parse(buffer.String()); err != nil {
+		return nil, err
+	}
+
+	for _, pt := range t.ListTemplates() {
+		buffer.Reset()
+		if err := pt.Source(&buffer); err != nil {
+			return nil, err
+		}
+		// keep the first line of the partial on line 1 of the parsed text
+		define := fmt.Sprintf("{{define %q}}{{\"\\n\"}}%s{{\"\\n\"}}{{end}}", tpl.PartialName(t, pt), buffer.String())
+		if _, err := tmpl.New(pt.Name()).Parse(define); err != nil {
+			return nil, err
+		}
 	}
-	return tmpl.Execute(outWriter, e.renderOptions)
+	return tmpl, nil
 }
@@ -1,9 +1,18 @@
 package renderer
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/joefitzgerald/inductor/configuration"
+	"github.com/joefitzgerald/inductor/floppy"
+	"github.com/joefitzgerald/inductor/iso"
 )
 
 // RenderOptions for packer.json and Autounattend.xml
@@ -15,6 +24,7 @@ type RenderOptions struct {
 	VirtualboxGuestOsType string
 	VmwareGuestOsType     string
 	IsoURL                string
+	IsoURLs               []string
 	IsoChecksumType       string
 	IsoChecksum           string
 	Communicator          string
@@ -25,10 +35,24 @@ type RenderOptions struct {
 	CPU                   uint8
 	Headless              bool
 	WindowsUpdates        bool
+	Strict                bool
+	FloppyFiles           []string
+	FloppyImage           string
+	CDFiles               []string
+	CDImage               string
+	Vars                  map[string]interface{}
+
+	// secrets are the values to redact from any output
+	secrets []string
+	// the sources of the password and product key, read by ResolveSecrets
+	passwordSource   configuration.Secret
+	productKeySource configuration.Secret
 }
 
 // NewRenderOptions creates render options using the base OS
-// registry with any provided overrides.
+// registry with any provided overrides. Build settings are resolved in order
+// from the global config, then the OS, then the edition, so the most specific
+// setting wins. Command line flags are applied on top by the caller.
 func NewRenderOptions(osname string, edition string, config *configuration.InductorConfiguration) (*RenderOptions, error) {
 	os, ok := config.Get(osname)
 	if !ok {
@@ -41,35 +65,287 @@ func NewRenderOptions(osname string, edition string, config *configuration.Induc
 	opts.Headless = config.Headless
 	opts.WindowsUpdates = config.WindowsUpdates
 	opts.Username = config.Username
-	opts.Password = config.Password
 	opts.DiskSize = config.DiskSize
 	opts.RAM = config.RAM
 	opts.CPU = config.CPU
+	opts.Strict = config.Strict
+	opts.FloppyFiles = config.FloppyFiles
+	opts.CDFiles = config.CDFiles
+	opts.SetVars(config.Vars)
 
 	// default all rendering options to values in the OS registry
 	opts.OSName = os.Name
 	opts.IsoChecksum = os.IsoChecksum
 	opts.IsoChecksumType = os.IsoChecksumType
-	opts.IsoURL = os.IsoURL
+	opts.IsoURLs = os.AllIsoURLs()
+	opts.IsoURL = ""
+	if len(opts.IsoURLs) > 0 {
+		opts.IsoURL = opts.IsoURLs[0]
+	}
 	opts.VirtualboxGuestOsType = os.VirtualboxGuestOsType
 	opts.VmwareGuestOsType = os.VmwareGuestOsType
 
 	// edition specific attributes
-	if len(edition) == 0 {
-		for k := range os.Editions {
-			edition = k
+	editionName, ed, err := os.ResolveEdition(edition)
+	if err != nil {
+		return nil, err
+	}
+	opts.Edition = editionName
+	opts.WindowsImageName = ed.WindowsImageName
+
+	// OS and then edition specific overrides of the global settings
+	opts.applySettings(os.Settings)
+	opts.applySettings(ed.Settings)
+
+	// the floppy and CD images are written to the output dir next to the
+	// templates
+	if len(opts.FloppyFiles) > 0 {
+		opts.FloppyImage = floppy.DefaultName
+	}
+	if len(opts.CDFiles) > 0 {
+		opts.CDImage = iso.DefaultCDName
+	}
+
+	// secret sources are only read by ResolveSecrets when rendering, so
+	// validating the configuration doesn't run commands or need the secrets
+	password := config.Password
+	if os.Password != nil {
+		password = *os.Password
+	}
+	if ed.Password != nil {
+		password = *ed.Password
+	}
+	opts.passwordSource = password
+	opts.productKeySource = ed.ProductKey
+	opts.Password = password.Value
+	opts.ProductKey = ed.ProductKey.Value
+
+	return opts, nil
+}
+
+// ResolveSecrets reads the password and product key from their secret
+// sources, marking them as secret so they're redacted from any output
+func (opts *RenderOptions) ResolveSecrets() error {
+	var err error
+	if opts.Password, err = opts.resolveSecret("password", opts.passwordSource); err != nil {
+		return err
+	}
+	if len(opts.Password) > 0 {
+		// the encoded answer file passwords are as secret as the password
+		opts.MarkSecret(EncodePassword(opts.Password))
+		opts.MarkSecret(EncodeAdministratorPassword(opts.Password))
+	}
+	opts.ProductKey, err = opts.resolveSecret("product key", opts.productKeySource)
+	return err
+}
+
+// SetProductKey overrides the configured product key, e.g. with a product key
+// given on the command line
+func (opts *RenderOptions) SetProductKey(productKey string) {
+	opts.ProductKey = productKey
+	opts.productKeySource = configuration.Secret{Value: productKey}
+}
+
+// resolveSecret reads the secret from its source, marking the value as secret
+func (opts *RenderOptions) resolveSecret(name string, s configuration.Secret) (string, error) {
+	value, err := s.Resolve()
+	if err != nil {
+		return "", fmt.Errorf("Couldn't resolve the %s for '%s': %s", name, opts.OSName, err)
+	}
+	opts.MarkSecret(value)
+	return value, nil
+}
+
+func (opts *RenderOptions) applySettings(s configuration.Settings) {
+	if s.Headless != nil {
+		opts.Headless = *s.Headless
+	}
+	if s.WindowsUpdates != nil {
+		opts.WindowsUpdates = *s.WindowsUpdates
+	}
+	if s.Communicator != nil {
+		opts.Communicator = *s.Communicator
+	}
+	if s.Username != nil {
+		opts.Username = *s.Username
+	}
+	if s.DiskSize != nil {
+		opts.DiskSize = *s.DiskSize
+	}
+	if s.RAM != nil {
+		opts.RAM = *s.RAM
+	}
+	if s.CPU != nil {
+		opts.CPU = *s.CPU
+	}
+	if s.FloppyFiles != nil {
+		opts.FloppyFiles = s.FloppyFiles
+	}
+	if s.CDFiles != nil {
+		opts.CDFiles = s.CDFiles
+	}
+	opts.SetVars(s.Vars)
+}
+
+// SetVars sets the template variables, overwriting any existing variables
+// with the same name
+func (opts *RenderOptions) SetVars(vars map[string]interface{}) {
+	if opts.Vars == nil {
+		opts.Vars = make(map[string]interface{})
+	}
+	for k, v := range vars {
+		opts.Vars[k] = v
+	}
+}
+
+// MarkSecret redacts the value from any output, e.g. a product key given on
+// the command line
+func (opts *RenderOptions) MarkSecret(value string) {
+	if len(value) > 0 {
+		opts.secrets = append(opts.secrets, value)
+	}
+}
+
+// Redact replaces every secret value in s which isn't part of a longer word,
+// so a short secret such as "admin" doesn't mangle e.g. "administrator". A
+// secret which is also the value of an option that isn't secret, such as the
+// default vagrant password and username, isn't redacted as the option's value
+// would reveal it anyway.
+func (opts *RenderOptions) Redact(s string) string {
+	for _, secret := range opts.secrets {
+		if !opts.isOptionValue(secret) {
+			s = redactWord(s, secret)
+		}
+	}
+	return s
+}
+
+// isOptionValue returns true when the value is also the value of an option
+// which isn't secret
+func (opts *RenderOptions) isOptionValue(value string) bool {
+	options := []string{
+		opts.OSName,
+		opts.Edition,
+		opts.WindowsImageName,
+		opts.VirtualboxGuestOsType,
+		opts.VmwareGuestOsType,
+		opts.IsoChecksumType,
+		opts.IsoChecksum,
+		opts.Communicator,
+		opts.Username,
+	}
+	for _, option := range options {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
+
+func redactWord(s, secret string) string {
+	var out bytes.Buffer
+	start := 0
+	for pos := 0; ; {
+		i := strings.Index(s[pos:], secret)
+		if i < 0 {
 			break
 		}
+		i += pos
+		end := i + len(secret)
+		before, _ := utf8.DecodeLastRuneInString(s[:i])
+		after, _ := utf8.DecodeRuneInString(s[end:])
+		if isWordRune(before) || isWordRune(after) {
+			pos = i + 1
+			continue
+		}
+		out.WriteString(s[start:i])
+		out.WriteString(configuration.Redacted)
+		start, pos = end, end
 	}
+	out.WriteString(s[start:])
+	return out.String()
+}
 
-	// TODO: validate that the edition is valid
-	opts.Edition = edition
-	opts.WindowsImageName = os.Editions[edition].WindowsImageName
-	opts.ProductKey = os.Editions[edition].ProductKey
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
 
-	return opts, nil
+// UseLocalISO puts the local ISO before every other ISO URL, e.g. an ISO
+// from the cache, so Packer doesn't download it
+func (opts *RenderOptions) UseLocalISO(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	isoURL := "file://" + filepath.ToSlash(abs)
+	isoURLs := []string{isoURL}
+	for _, u := range opts.IsoURLs {
+		if u != isoURL {
+			isoURLs = append(isoURLs, u)
+		}
+	}
+	opts.IsoURL = isoURL
+	opts.IsoURLs = isoURLs
+	return nil
 }
 
+// CheckLocalISOs checks every file:// URL and local path of the ISO sources
+// exists, writing a warning for each one which doesn't. Relative paths are
+// relative to the current directory. It errors when there's no usable source
+// left, i.e. every source is a missing local ISO, as Packer would have nothing
+// to fall back to.
+func (opts *RenderOptions) CheckLocalISOs(warnings io.Writer) error {
+	missing := []string{}
+	for _, isoURL := range opts.IsoURLs {
+		path, ok := iso.LocalPath(isoURL)
+		if !ok {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			fmt.Fprintf(warnings, "Warning: the local ISO %s for '%s' doesn't exist\n", isoURL, opts.OSName)
+			missing = append(missing, isoURL)
+		}
+	}
+	if len(missing) > 0 && len(missing) == len(opts.IsoURLs) {
+		return fmt.Errorf("Local ISOs for '%s' don't exist: %s", opts.OSName, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Validate ensures all the required options have a value
+func (opts *RenderOptions) Validate() error {
+	required := []struct {
+		name  string
+		empty bool
+	}{
+		{"OSName", len(opts.OSName) == 0},
+		{"WindowsImageName", len(opts.WindowsImageName) == 0},
+		{"VirtualboxGuestOsType", len(opts.VirtualboxGuestOsType) == 0},
+		{"VmwareGuestOsType", len(opts.VmwareGuestOsType) == 0},
+		{"IsoURL", len(opts.IsoURL) == 0},
+		{"IsoChecksumType", len(opts.IsoChecksumType) == 0},
+		{"IsoChecksum", len(opts.IsoChecksum) == 0},
+		{"Communicator", len(opts.Communicator) == 0},
+		{"Username", len(opts.Username) == 0},
+		{"Password", len(opts.Password) == 0},
+		{"DiskSize", opts.DiskSize == 0},
+		{"RAM", opts.RAM == 0},
+		{"CPU", opts.CPU == 0},
+	}
+	missing := []string{}
+	for _, r := range required {
+		if r.empty {
+			missing = append(missing, r.name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("Required render options for '%s' are empty: %s", opts.OSName, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+const defaultIsoURL = "http://care.dlservice.microsoft.com/dl/download/C/3/9/C399EEA8-135D-4207-92C9-6AAB3259F6EF/10240.16384.150709-1700.TH1_CLIENTENTERPRISEEVAL_OEMRET_X64FRE_EN-US.ISO"
+
 // NewDefaultRenderOptions creates a new ready to use RenderOptions instance which
 // defaults to Windows10 trial values
 func NewDefaultRenderOptions() *RenderOptions {
@@ -79,7 +355,8 @@ func NewDefaultRenderOptions() *RenderOptions {
 		WindowsImageName:      "Windows 10 Enterprise Evaluation",
 		VirtualboxGuestOsType: "Windows81_64",
 		VmwareGuestOsType:     "windows8srv-64",
-		IsoURL:                "http://care.dlservice.microsoft.com/dl/download/C/3/9/C399EEA8-135D-4207-92C9-6AAB3259F6EF/10240.16384.150709-1700.TH1_CLIENTENTERPRISEEVAL_OEMRET_X64FRE_EN-US.ISO",
+		IsoURL:                defaultIsoURL,
+		IsoURLs:               []string{defaultIsoURL},
 		IsoChecksumType:       "sha1",
 		IsoChecksum:           "56ab095075be28a90bc0b510835280975c6bb2ce",
 		Communicator:          "winrm",
@@ -90,6 +367,7 @@ func NewDefaultRenderOptions() *RenderOptions {
 		CPU:                   2,
 		Headless:              true,
 		WindowsUpdates:        true,
+		Vars:                  make(map[string]interface{}),
 	}
 	return ro
 }
@@ -0,0 +1,301 @@
+package renderer_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/joefitzgerald/inductor/configuration"
+	"github.com/joefitzgerald/inductor/renderer"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RenderOptions", func() {
+	var (
+		err    error
+		opts   *renderer.RenderOptions
+		config *configuration.InductorConfiguration
+	)
+
+	BeforeEach(func() {
+		ram := uint32(4096)
+		cpu := uint8(4)
+		editionCPU := uint8(8)
+		headless := false
+		config = &configuration.InductorConfiguration{
+			Communicator: "winrm",
+			Username:     "vagrant",
+			Password:     configuration.Secret{Value: "vagrant"},
+			Headless:     true,
+			RAM:          2048,
+			CPU:          2,
+			Vars:         map[string]interface{}{"timezone": "UTC", "locale": "en-US"},
+			OperatingSystems: map[string]configuration.OperatingSystem{
+				"windows2012r2": {
+					Name:           "windows2012r2",
+					IsoURL:         "http://example.com/windows2012r2.iso",
+					IsoURLs:        []string{"file:///nas/windows2012r2.iso"},
+					DefaultEdition: "standard",
+					Settings: configuration.Settings{
+						RAM:  &ram,
+						CPU:  &cpu,
+						Vars: map[string]interface{}{"locale": "en-GB"},
+					},
+					Editions: map[string]configuration.Edition{
+						"datacenter": {
+							WindowsImageName: "Windows Server 2012 R2 SERVERDATACENTER",
+							Settings: configuration.Settings{
+								CPU:      &editionCPU,
+								Headless: &headless,
+								Vars:     map[string]interface{}{"timezone": "GMT Standard Time"},
+							},
+						},
+						"standard": {WindowsImageName: "Windows Server 2012 R2 SERVERSTANDARD", ProductKey: configuration.Secret{Value: "KEY"}},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("SetVars", func() {
+		It("should overwrite existing vars", func() {
+			opts = renderer.NewDefaultRenderOptions()
+			opts.SetVars(map[string]interface{}{"timezone": "UTC", "org": "ACME"})
+			opts.SetVars(map[string]interface{}{"timezone": "Pacific Standard Time"})
+			Expect(opts.Vars).To(Equal(map[string]interface{}{"timezone": "Pacific Standard Time", "org": "ACME"}))
+		})
+	})
+
+	Describe("UseLocalISO", func() {
+		It("should put the local ISO first", func() {
+			opts = renderer.NewDefaultRenderOptions()
+			remote := opts.IsoURL
+			Expect(opts.UseLocalISO("/cache/sha1-abc.iso")).To(Succeed())
+			Expect(opts.IsoURL).To(HavePrefix("file://"))
+			Expect(opts.IsoURL).To(HaveSuffix("/cache/sha1-abc.iso"))
+			Expect(opts.IsoURLs).To(Equal([]string{opts.IsoURL, remote}))
+		})
+	})
+
+	Describe("CheckLocalISOs", func() {
+		var (
+			isoPath  string
+			warnings bytes.Buffer
+		)
+		BeforeEach(func() {
+			f, err := ioutil.TempFile("", "inductor")
+			Expect(err).NotTo(HaveOccurred())
+			f.Close()
+			isoPath = f.Name()
+			warnings.Reset()
+			opts = renderer.NewDefaultRenderOptions()
+			opts.OSName = "windows10"
+		})
+		AfterEach(func() {
+			os.Remove(isoPath)
+		})
+		It("should warn about every missing local ISO", func() {
+			opts.IsoURLs = []string{"//nas/isos/windows10.iso", "file://" + filepath.ToSlash(isoPath), "isos/missing.iso", "http://mirror/windows10.iso"}
+			Expect(opts.CheckLocalISOs(&warnings)).To(Succeed())
+			Expect(warnings.String()).To(Equal("Warning: the local ISO //nas/isos/windows10.iso for 'windows10' doesn't exist\n" +
+				"Warning: the local ISO isos/missing.iso for 'windows10' doesn't exist\n"))
+		})
+		It("should not warn when every local ISO exists", func() {
+			opts.IsoURLs = []string{isoPath, "http://mirror/windows10.iso"}
+			Expect(opts.CheckLocalISOs(&warnings)).To(Succeed())
+			Expect(warnings.String()).To(BeEmpty())
+		})
+		It("should error when there's no usable source left", func() {
+			opts.IsoURLs = []string{"isos/missing.iso"}
+			Expect(opts.CheckLocalISOs(&warnings)).To(MatchError("Local ISOs for 'windows10' don't exist: isos/missing.iso"))
+			Expect(warnings.String()).To(ContainSubstring("isos/missing.iso"))
+		})
+	})
+
+	Describe("NewRenderOptions", func() {
+		Context("without an edition", func() {
+			BeforeEach(func() {
+				opts, err = renderer.NewRenderOptions("windows2012r2", "", config)
+			})
+			It("should not have errored", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+			It("should use the default edition", func() {
+				Expect(opts.Edition).To(Equal("standard"))
+				Expect(opts.WindowsImageName).To(Equal("Windows Server 2012 R2 SERVERSTANDARD"))
+				Expect(opts.ProductKey).To(Equal("KEY"))
+			})
+			It("should use the OS settings over the global settings", func() {
+				Expect(opts.RAM).To(Equal(uint32(4096)))
+				Expect(opts.CPU).To(Equal(uint8(4)))
+			})
+			It("should merge the OS vars over the global vars", func() {
+				Expect(opts.Vars).To(Equal(map[string]interface{}{"timezone": "UTC", "locale": "en-GB"}))
+			})
+			It("should list the ISO URL first", func() {
+				Expect(opts.IsoURL).To(Equal("http://example.com/windows2012r2.iso"))
+				Expect(opts.IsoURLs).To(Equal([]string{"http://example.com/windows2012r2.iso", "file:///nas/windows2012r2.iso"}))
+			})
+			It("should not have a floppy image without floppy files", func() {
+				Expect(opts.FloppyImage).To(BeEmpty())
+			})
+			It("should not have a CD image without CD files", func() {
+				Expect(opts.CDImage).To(BeEmpty())
+			})
+			It("should use global settings the OS doesn't override", func() {
+				Expect(opts.Headless).To(BeTrue())
+				Expect(opts.Communicator).To(Equal("winrm"))
+			})
+		})
+		Context("with an edition", func() {
+			BeforeEach(func() {
+				opts, err = renderer.NewRenderOptions("windows2012r2", "datacenter", config)
+			})
+			It("should use the requested edition", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(opts.Edition).To(Equal("datacenter"))
+				Expect(opts.WindowsImageName).To(Equal("Windows Server 2012 R2 SERVERDATACENTER"))
+			})
+			It("should use the edition settings over the OS and global settings", func() {
+				Expect(opts.CPU).To(Equal(uint8(8)))
+				Expect(opts.Headless).To(BeFalse())
+				Expect(opts.RAM).To(Equal(uint32(4096)))
+			})
+			It("should merge the edition vars over the OS and global vars", func() {
+				Expect(opts.Vars).To(Equal(map[string]interface{}{"timezone": "GMT Standard Time", "locale": "en-GB"}))
+			})
+		})
+		Context("with an unknown edition", func() {
+			BeforeEach(func() {
+				opts, err = renderer.NewRenderOptions("windows2012r2", "essentials", config)
+			})
+			It("should return an unknown edition error", func() {
+				Expect(err).To(BeAssignableToTypeOf(&configuration.UnknownEditionError{}))
+				Expect(err.Error()).To(ContainSubstring("datacenter, standard"))
+				Expect(opts).To(BeNil())
+			})
+		})
+		Context("with floppy files", func() {
+			BeforeEach(func() {
+				config.FloppyFiles = []string{"Autounattend.xml"}
+				ed := config.OperatingSystems["windows2012r2"].Editions["datacenter"]
+				ed.FloppyFiles = []string{"Autounattend.xml", "scripts/*.ps1"}
+				config.OperatingSystems["windows2012r2"].Editions["datacenter"] = ed
+			})
+			It("should use the most specific floppy files", func() {
+				opts, err = renderer.NewRenderOptions("windows2012r2", "standard", config)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(opts.FloppyFiles).To(Equal([]string{"Autounattend.xml"}))
+				opts, err = renderer.NewRenderOptions("windows2012r2", "datacenter", config)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(opts.FloppyFiles).To(Equal([]string{"Autounattend.xml", "scripts/*.ps1"}))
+			})
+			It("should expose the floppy image path", func() {
+				opts, err = renderer.NewRenderOptions("windows2012r2", "standard", config)
+				Expect(opts.FloppyImage).To(Equal("floppy.vfd"))
+			})
+		})
+		Context("with CD files", func() {
+			BeforeEach(func() {
+				windows := config.OperatingSystems["windows2012r2"]
+				windows.CDFiles = []string{"Autounattend.xml", "scripts/*"}
+				config.OperatingSystems["windows2012r2"] = windows
+			})
+			It("should use the OS CD files and expose the CD image path", func() {
+				opts, err = renderer.NewRenderOptions("windows2012r2", "standard", config)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(opts.CDFiles).To(Equal([]string{"Autounattend.xml", "scripts/*"}))
+				Expect(opts.CDImage).To(Equal("unattend.iso"))
+			})
+		})
+		Context("with secret sources", func() {
+			BeforeEach(func() {
+				os.Setenv("INDUCTOR_TEST_PRODUCT_KEY", "ABCDE-12345")
+				ed := config.OperatingSystems["windows2012r2"].Editions["standard"]
+				ed.ProductKey = configuration.Secret{Env: "INDUCTOR_TEST_PRODUCT_KEY"}
+				ed.Password = &configuration.Secret{Command: "echo hunter2"}
+				config.OperatingSystems["windows2012r2"].Editions["standard"] = ed
+				opts, err = renderer.NewRenderOptions("windows2012r2", "standard", config)
+				Expect(err).NotTo(HaveOccurred())
+				err = opts.ResolveSecrets()
+			})
+			AfterEach(func() {
+				os.Unsetenv("INDUCTOR_TEST_PRODUCT_KEY")
+			})
+			It("should resolve the secrets", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(opts.ProductKey).To(Equal("ABCDE-12345"))
+				Expect(opts.Password).To(Equal("hunter2"))
+			})
+			It("should redact the secret values only", func() {
+				Expect(opts.Redact("vagrant hunter2 ABCDE-12345")).To(Equal("vagrant ******** ********"))
+			})
+			It("should redact the encoded passwords", func() {
+				encoded := renderer.EncodePassword("hunter2") + " " + renderer.EncodeAdministratorPassword("hunter2")
+				Expect(opts.Redact(encoded)).To(Equal("******** ********"))
+			})
+			It("should not redact secrets which are part of a longer word", func() {
+				Expect(opts.Redact("hunter2s hunter2: xhunter2 \"hunter2\"")).To(Equal("hunter2s ********: xhunter2 \"********\""))
+			})
+			It("should use a product key override instead of the source", func() {
+				os.Unsetenv("INDUCTOR_TEST_PRODUCT_KEY")
+				opts.SetProductKey("FGHIJ-67890")
+				Expect(opts.ResolveSecrets()).To(Succeed())
+				Expect(opts.ProductKey).To(Equal("FGHIJ-67890"))
+				Expect(opts.Redact("FGHIJ-67890")).To(Equal("********"))
+			})
+		})
+		Context("with a plain password", func() {
+			BeforeEach(func() {
+				config.Password = configuration.Secret{Value: "s3cret"}
+				opts, err = renderer.NewRenderOptions("windows2012r2", "standard", config)
+				Expect(err).NotTo(HaveOccurred())
+				err = opts.ResolveSecrets()
+			})
+			It("should redact the password", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(opts.Redact("username = vagrant\npassword = s3cret")).To(Equal("username = vagrant\npassword = ********"))
+			})
+		})
+		Context("with a password which is also the username", func() {
+			BeforeEach(func() {
+				opts, err = renderer.NewRenderOptions("windows2012r2", "standard", config)
+				Expect(err).NotTo(HaveOccurred())
+				err = opts.ResolveSecrets()
+			})
+			It("should not redact the username", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(opts.Redact("username = vagrant\npassword = vagrant")).To(Equal("username = vagrant\npassword = vagrant"))
+			})
+			It("should redact the encoded password", func() {
+				Expect(opts.Redact(renderer.EncodePassword("vagrant"))).To(Equal("********"))
+			})
+		})
+		Context("with an unresolvable secret", func() {
+			BeforeEach(func() {
+				ed := config.OperatingSystems["windows2012r2"].Editions["standard"]
+				ed.ProductKey = configuration.Secret{Env: "INDUCTOR_TEST_UNSET"}
+				config.OperatingSystems["windows2012r2"].Editions["standard"] = ed
+				opts, err = renderer.NewRenderOptions("windows2012r2", "standard", config)
+			})
+			It("should not read the secret until it's resolved", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+			It("should error resolving the secret", func() {
+				err = opts.ResolveSecrets()
+				Expect(err).To(MatchError("Couldn't resolve the product key for 'windows2012r2': Secret environment variable 'INDUCTOR_TEST_UNSET' is not set"))
+			})
+		})
+		Context("with an unknown OS", func() {
+			BeforeEach(func() {
+				opts, err = renderer.NewRenderOptions("windows95", "", config)
+			})
+			It("should error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
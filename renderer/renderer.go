@@ -2,7 +2,7 @@ package renderer
 
 import "github.com/joefitzgerald/inductor/tpl"
 
-// Renderer will render the given set of templates to disk
+// Renderer will render the given set of templates to an output
 type Renderer interface {
 	Render(templates tpl.TemplateContainer) error
 }
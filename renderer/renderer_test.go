@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/joefitzgerald/inductor/output"
 	"github.com/joefitzgerald/inductor/renderer"
 	"github.com/joefitzgerald/inductor/tpl"
 	"github.com/joefitzgerald/inductor/tpl/fakes"
@@ -31,11 +32,11 @@ var _ = Describe("Renderer", func() {
 			vagrantfilePath = filepath.Join(outDir, "Vagrantfile")
 			renderOptions = renderer.NewDefaultRenderOptions()
 			vagrantTemplate := new(fakes.FakeTemplater)
-			vagrantTemplate.ContentStub = writeVagrantfile
+			vagrantTemplate.SourceStub = writeVagrantfile
 			vagrantTemplate.BaseFilenameReturns("Vagrantfile")
 			templates = new(fakes.FakeTemplateContainer)
 			templates.ListTemplatesReturns([]tpl.Templater{vagrantTemplate})
-			engine = renderer.New(renderOptions, outDir)
+			engine = renderer.New(renderOptions, output.NewDir(outDir))
 			err = engine.Render(templates)
 		})
 		AfterEach(func() {
@@ -53,6 +54,248 @@ var _ = Describe("Renderer", func() {
 			Expect(string(bytes)).To(ContainSubstring("Vagrant.configure(\"2\") do |config|"))
 		})
 	})
+
+	Describe("Templates in sub directories", func() {
+		var (
+			scriptTemplate *fakes.FakeTemplater
+			otherTemplate  *fakes.FakeTemplater
+		)
+		BeforeEach(func() {
+			outDir, err = ioutil.TempDir("", "inductor")
+			Expect(err).NotTo(HaveOccurred())
+			renderOptions = renderer.NewDefaultRenderOptions()
+			scriptTemplate = new(fakes.FakeTemplater)
+			scriptTemplate.NameReturns("scripts/enable-winrm.ps1.template")
+			scriptTemplate.BaseFilenameReturns("enable-winrm.ps1")
+			otherTemplate = new(fakes.FakeTemplater)
+			otherTemplate.NameReturns("scripts/nano/enable-winrm.ps1.template")
+			otherTemplate.BaseFilenameReturns("enable-winrm.ps1")
+		})
+		JustBeforeEach(func() {
+			templates = new(fakes.FakeTemplateContainer)
+			templates.ListTemplatesReturns([]tpl.Templater{scriptTemplate, otherTemplate})
+			engine = renderer.New(renderOptions, output.NewDir(outDir))
+			err = engine.Render(templates)
+		})
+		AfterEach(func() {
+			os.RemoveAll(outDir)
+		})
+		It("should not have errored", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("should render to the same relative dir in the out dir", func() {
+			Expect(filepath.Join(outDir, "scripts", "enable-winrm.ps1")).To(BeARegularFile())
+			Expect(filepath.Join(outDir, "scripts", "nano", "enable-winrm.ps1")).To(BeARegularFile())
+		})
+		It("should not render to the root of the out dir", func() {
+			Expect(filepath.Join(outDir, "enable-winrm.ps1")).NotTo(BeAnExistingFile())
+		})
+		Context("when two templates render to the same file", func() {
+			BeforeEach(func() {
+				otherTemplate.NameReturns("scripts/Enable-WinRM.ps1.template")
+				otherTemplate.BaseFilenameReturns("Enable-WinRM.ps1")
+			})
+			It("should error", func() {
+				Expect(err).To(MatchError(ContainSubstring("both render to scripts/Enable-WinRM.ps1")))
+			})
+			It("should not write any files", func() {
+				Expect(filepath.Join(outDir, "scripts")).NotTo(BeADirectory())
+			})
+		})
+		Context("when a template renders to a copied file", func() {
+			JustBeforeEach(func() {
+				engine = renderer.NewWithCopies(renderOptions, output.NewDir(outDir), []string{"Vagrantfile", "scripts/Enable-WinRM.ps1"})
+				err = engine.Render(templates)
+			})
+			It("should error", func() {
+				Expect(err).To(MatchError("Template scripts/enable-winrm.ps1.template renders to scripts/enable-winrm.ps1 which is also copied from scripts/Enable-WinRM.ps1"))
+			})
+		})
+	})
+
+	Describe("Template with partials", func() {
+		var (
+			partialContent string
+			rootTemplate   *fakes.FakeTemplater
+		)
+		BeforeEach(func() {
+			outDir, err = ioutil.TempDir("", "inductor")
+			Expect(err).NotTo(HaveOccurred())
+			renderOptions = renderer.NewDefaultRenderOptions()
+			partialContent = "<DiskID>0</DiskID>\n<OS>{{.OSName}}</OS>"
+		})
+		JustBeforeEach(func() {
+			rootTemplate = new(fakes.FakeTemplater)
+			rootTemplate.NameReturns("Autounattend.xml.template")
+			rootTemplate.BaseFilenameReturns("Autounattend.xml")
+			rootTemplate.SourceStub = func(buffer io.Writer) error {
+				_, err := buffer.Write([]byte("<unattend>{{template \"disks\" .}}</unattend>"))
+				return err
+			}
+			partialTemplate := new(fakes.FakeTemplater)
+			partialTemplate.NameReturns("windows10/Autounattend.xml.disks.partial")
+			partialTemplate.BaseFilenameReturns("Autounattend.xml.disks")
+			partialTemplate.SourceStub = func(buffer io.Writer) error {
+				_, err := buffer.Write([]byte(partialContent))
+				return err
+			}
+			rootTemplate.ListTemplatesReturns([]tpl.Templater{partialTemplate})
+			templates = new(fakes.FakeTemplateContainer)
+			templates.ListTemplatesReturns([]tpl.Templater{rootTemplate})
+			engine = renderer.New(renderOptions, output.NewDir(outDir))
+			err = engine.Render(templates)
+		})
+		AfterEach(func() {
+			os.RemoveAll(outDir)
+		})
+		It("should render the partial into the root template", func() {
+			Expect(err).NotTo(HaveOccurred())
+			bytes, err := ioutil.ReadFile(filepath.Join(outDir, "Autounattend.xml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(bytes)).To(Equal("<unattend>\n<DiskID>0</DiskID>\n<OS>windows10</OS>\n</unattend>"))
+		})
+		Context("with a parse error in the partial", func() {
+			BeforeEach(func() {
+				partialContent = "<DiskID>0</DiskID>\n<OS>{{.OSName</OS>"
+			})
+			It("should report the partial file and line", func() {
+				Expect(err).To(MatchError(ContainSubstring("windows10/Autounattend.xml.disks.partial:2")))
+			})
+		})
+		Context("with an exec error in the partial", func() {
+			BeforeEach(func() {
+				partialContent = "<DiskID>0</DiskID>\n\n<OS>{{.OSNmae}}</OS>"
+			})
+			It("should report the partial file and line", func() {
+				Expect(err).To(MatchError(ContainSubstring("windows10/Autounattend.xml.disks.partial:3")))
+			})
+		})
+	})
+
+	Describe("Strict mode", func() {
+		var content string
+		BeforeEach(func() {
+			outDir, err = ioutil.TempDir("", "inductor")
+			Expect(err).NotTo(HaveOccurred())
+			renderOptions = renderer.NewDefaultRenderOptions()
+			renderOptions.Strict = true
+			content = "box = {{.OSName}}"
+		})
+		JustBeforeEach(func() {
+			packerTemplate := new(fakes.FakeTemplater)
+			packerTemplate.SourceStub = func(buffer io.Writer) error {
+				_, err := buffer.Write([]byte(content))
+				return err
+			}
+			packerTemplate.NameReturns("packer.json.template")
+			packerTemplate.BaseFilenameReturns("packer.json")
+			templates = new(fakes.FakeTemplateContainer)
+			templates.ListTemplatesReturns([]tpl.Templater{packerTemplate})
+			engine = renderer.New(renderOptions, output.NewDir(outDir))
+			err = engine.Render(templates)
+		})
+		AfterEach(func() {
+			os.RemoveAll(outDir)
+		})
+		It("should render valid templates", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filepath.Join(outDir, "packer.json")).To(BeARegularFile())
+		})
+		Context("with empty required options", func() {
+			BeforeEach(func() {
+				renderOptions.IsoURL = ""
+				renderOptions.Password = ""
+			})
+			It("should error listing the empty options", func() {
+				Expect(err).To(MatchError(ContainSubstring("IsoURL, Password")))
+			})
+			It("should not write any files", func() {
+				Expect(filepath.Join(outDir, "packer.json")).NotTo(BeAnExistingFile())
+			})
+		})
+		Context("with a template rendering <no value>", func() {
+			BeforeEach(func() {
+				content = "box = {{.OSName}}\niso = <no value>"
+			})
+			It("should error with the template and line", func() {
+				Expect(err).To(MatchError(ContainSubstring("packer.json.template:2 rendered <no value>")))
+			})
+			It("should not write any files", func() {
+				Expect(filepath.Join(outDir, "packer.json")).NotTo(BeAnExistingFile())
+			})
+		})
+		Context("when not strict", func() {
+			BeforeEach(func() {
+				renderOptions.Strict = false
+				renderOptions.IsoURL = ""
+				content = "iso = <no value>"
+			})
+			It("should render anyway", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(filepath.Join(outDir, "packer.json")).To(BeARegularFile())
+			})
+		})
+	})
+
+	Describe("ISO URLs", func() {
+		var isoPath string
+		BeforeEach(func() {
+			outDir, err = ioutil.TempDir("", "inductor")
+			Expect(err).NotTo(HaveOccurred())
+			isoPath = filepath.Join(outDir, "windows10.iso")
+			Expect(ioutil.WriteFile(isoPath, []byte("iso"), 0644)).To(Succeed())
+			renderOptions = renderer.NewDefaultRenderOptions()
+			renderOptions.IsoURLs = []string{"file://" + filepath.ToSlash(isoPath), "http://mirror/windows10.iso"}
+		})
+		JustBeforeEach(func() {
+			packerTemplate := new(fakes.FakeTemplater)
+			packerTemplate.SourceStub = func(buffer io.Writer) error {
+				_, err := buffer.Write([]byte(`"iso_urls": [{{range $i, $url := .IsoURLs}}{{if $i}}, {{end}}"{{$url}}"{{end}}]`))
+				return err
+			}
+			packerTemplate.BaseFilenameReturns("packer.json")
+			templates = new(fakes.FakeTemplateContainer)
+			templates.ListTemplatesReturns([]tpl.Templater{packerTemplate})
+			engine = renderer.New(renderOptions, output.NewDir(outDir))
+			err = engine.Render(templates)
+		})
+		AfterEach(func() {
+			os.RemoveAll(outDir)
+		})
+		It("should render every URL", func() {
+			Expect(err).NotTo(HaveOccurred())
+			bytes, err := ioutil.ReadFile(filepath.Join(outDir, "packer.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(bytes)).To(Equal(`"iso_urls": ["file://` + filepath.ToSlash(isoPath) + `", "http://mirror/windows10.iso"]`))
+		})
+		Context("with a missing local ISO and a mirror", func() {
+			BeforeEach(func() {
+				renderOptions.IsoURLs = []string{"isos/missing.iso", "http://mirror/windows10.iso"}
+			})
+			It("should render using the mirror", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+			Context("when strict", func() {
+				BeforeEach(func() {
+					renderOptions.Strict = true
+				})
+				It("should render using the mirror", func() {
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+		})
+		Context("with only missing local ISOs", func() {
+			BeforeEach(func() {
+				renderOptions.IsoURLs = []string{"isos/missing.iso", "file:///isos/missing.iso"}
+			})
+			It("should error listing the missing ISOs", func() {
+				Expect(err).To(MatchError("Local ISOs for 'windows10' don't exist: isos/missing.iso, file:///isos/missing.iso"))
+			})
+			It("should not write any file", func() {
+				Expect(filepath.Join(outDir, "packer.json")).NotTo(BeAnExistingFile())
+			})
+		})
+	})
 })
 
 func writeVagrantfile(buffer io.Writer) error {
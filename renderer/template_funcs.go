@@ -1,16 +1,54 @@
 package renderer
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
 	"text/template"
+	"unicode/utf16"
 )
 
 var templateFuncs = template.FuncMap{
-	"Contains":         strings.Contains,
-	"Replace":          strings.Replace,
-	"ToUpper":          strings.ToUpper,
-	"ToLower":          strings.ToLower,
-	"SafeComputerName": SafeComputerName,
+	"Contains":                    strings.Contains,
+	"Replace":                     strings.Replace,
+	"ToUpper":                     strings.ToUpper,
+	"ToLower":                     strings.ToLower,
+	"SafeComputerName":            SafeComputerName,
+	"EncodePassword":              EncodePassword,
+	"EncodeAdministratorPassword": EncodeAdministratorPassword,
+	"Default":                     Default,
+	"Coalesce":                    Coalesce,
+	"Required":                    Required,
+	"ToJSON":                      ToJSON,
+	"EscapeJSON":                  EscapeJSON,
+	"EscapeXML":                   EscapeXML,
+	"Indent":                      Indent,
+	"NIndent":                     NIndent,
+	"Split":                       Split,
+	"Join":                        Join,
+	"List":                        List,
+	"Dict":                        Dict,
+	"Base64Encode":                Base64Encode,
+	"Base64Decode":                Base64Decode,
+	"Sha256":                      Sha256,
+	"UUID":                        UUID,
+	"UUIDFromName":                UUIDFromName,
+	"RegexMatch":                  RegexMatch,
+	"RegexReplace":                RegexReplace,
+	"GBToMB":                      GBToMB,
+	"MBToGB":                      MBToGB,
 }
 
 // SafeComputerName modifies the specified string to make it Windows computer
@@ -29,3 +67,274 @@ func SafeComputerName(name string) string {
 	}
 	return name[0:i]
 }
+
+// EncodePassword encodes a user account or auto logon password the way
+// Windows Setup expects an answer file password with
+// <PlainText>false</PlainText>, base64 of the UTF-16LE password followed by
+// "Password"
+func EncodePassword(password string) string {
+	return encodeUnattendPassword(password + "Password")
+}
+
+// EncodeAdministratorPassword encodes the built-in administrator password the
+// way Windows Setup expects, base64 of the UTF-16LE password followed by
+// "AdministratorPassword"
+func EncodeAdministratorPassword(password string) string {
+	return encodeUnattendPassword(password + "AdministratorPassword")
+}
+
+func encodeUnattendPassword(s string) string {
+	units := utf16.Encode([]rune(s))
+	b := make([]byte, len(units)*2)
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(b[i*2:], u)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// Default returns the value, or def when the value is empty. The value is
+// last so it can be piped, e.g. {{.Vars.timezone | Default "UTC"}}
+func Default(def, value interface{}) interface{} {
+	if isEmpty(value) {
+		return def
+	}
+	return value
+}
+
+// Coalesce returns the first value which isn't empty, or nil
+func Coalesce(values ...interface{}) interface{} {
+	for _, v := range values {
+		if !isEmpty(v) {
+			return v
+		}
+	}
+	return nil
+}
+
+// Required returns the value, failing the render with the message when the
+// value is empty, e.g. {{.Vars.domain | Required "vars.domain must be set"}}
+func Required(message string, value interface{}) (interface{}, error) {
+	if isEmpty(value) {
+		return nil, errors.New(message)
+	}
+	return value, nil
+}
+
+// isEmpty returns true for nil, false, zero numbers and empty strings, slices
+// and maps
+func isEmpty(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Array, reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// ToJSON encodes the value as JSON, e.g. to render a list of vars into a
+// Packer template
+func ToJSON(value interface{}) (string, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// EscapeJSON escapes the string to go between the quotes of a JSON string
+func EscapeJSON(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b[1 : len(b)-1])
+}
+
+// EscapeXML escapes the string for XML text or attribute values, e.g. a
+// password in Autounattend.xml
+func EscapeXML(s string) (string, error) {
+	var buffer bytes.Buffer
+	if err := xml.EscapeText(&buffer, []byte(s)); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// Indent indents every non-empty line of the string by the number of spaces
+func Indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if len(line) > 0 {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// NIndent is Indent starting on a new line, e.g. to include a partial
+// template at the indentation of the current line
+func NIndent(spaces int, s string) string {
+	return "\n" + Indent(spaces, s)
+}
+
+// Split splits the string around each separator, e.g.
+// {{range .Vars.features | Split ","}}
+func Split(sep, s string) []string {
+	return strings.Split(s, sep)
+}
+
+// Join joins the items of a list of any type with the separator
+func Join(sep string, list interface{}) (string, error) {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return "", fmt.Errorf("Join expects a list, got %T", list)
+	}
+	items := make([]string, v.Len())
+	for i := range items {
+		items[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return strings.Join(items, sep), nil
+}
+
+// List creates a list of the items
+func List(items ...interface{}) []interface{} {
+	return items
+}
+
+// Dict creates a map from key value pairs, e.g. to pass several values to a
+// partial template with {{template "disk" Dict "size" .DiskSize "index" 0}}
+func Dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("Dict expects key value pairs")
+	}
+	dict := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("Dict keys must be strings, got %T", pairs[i])
+		}
+		dict[key] = pairs[i+1]
+	}
+	return dict, nil
+}
+
+// Base64Encode encodes the string as standard base64
+func Base64Encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// Base64Decode decodes standard base64
+func Base64Decode(s string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Sha256 is the hex SHA-256 hash of the string
+func Sha256(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// UUID generates a random version 4 UUID. The rendered output changes every
+// time, use UUIDFromName for a UUID which is the same on every render.
+func UUID() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", err
+	}
+	return formatUUID(u, 4), nil
+}
+
+// uuidNamespaceURL is the RFC 4122 namespace of URL names
+var uuidNamespaceURL = []byte{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+// UUIDFromName generates the version 5 UUID of the name in the URL
+// namespace, which is the same for the same name on every render
+func UUIDFromName(name string) string {
+	h := sha1.New()
+	h.Write(uuidNamespaceURL)
+	h.Write([]byte(name))
+	var u [16]byte
+	copy(u[:], h.Sum(nil))
+	return formatUUID(u, 5)
+}
+
+func formatUUID(u [16]byte, version byte) string {
+	u[6] = u[6]&0x0f | version<<4
+	// the RFC 4122 variant
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// RegexMatch returns true when the regular expression matches the string
+func RegexMatch(pattern, s string) (bool, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(s), nil
+}
+
+// RegexReplace replaces every match of the regular expression in the string,
+// the replacement can refer to submatches with $1
+func RegexReplace(pattern, replacement, s string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.ReplaceAllString(s, replacement), nil
+}
+
+// GBToMB converts gigabytes to megabytes, e.g. {{GBToMB 40}} for a 40GB
+// disk_size
+func GBToMB(gb interface{}) (string, error) {
+	f, err := toFloat(gb)
+	if err != nil {
+		return "", err
+	}
+	return formatSize(f * 1024), nil
+}
+
+// MBToGB converts megabytes to gigabytes, e.g. {{MBToGB .DiskSize}}
+func MBToGB(mb interface{}) (string, error) {
+	f, err := toFloat(mb)
+	if err != nil {
+		return "", err
+	}
+	return formatSize(f / 1024), nil
+}
+
+// formatSize formats a size without an exponent, e.g. 1024000 rather than
+// 1.024e+06, and without a fraction when it's a whole number
+func formatSize(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// toFloat converts any number, e.g. an int literal, a uint32 setting or a
+// float64 from a JSON var, to a float64
+func toFloat(value interface{}) (float64, error) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	}
+	return 0, fmt.Errorf("Expected a number, got %T", value)
+}
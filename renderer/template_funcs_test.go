@@ -1,6 +1,13 @@
 package renderer
 
-import "testing"
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"text/template"
+)
 
 func TestSafeComputerNameThatIsTooLong(t *testing.T) {
 	invalidComputerName := "/\\*lo<n>g|with?invalidcharsandtoolong"
@@ -17,3 +24,232 @@ func TestSafeComputerName(t *testing.T) {
 		t.Errorf("Expected computer name '%s', but got '%s'", computerName, actual)
 	}
 }
+
+// The expected values are the encoded passwords Windows System Image Manager
+// writes to an answer file
+func TestEncodePassword(t *testing.T) {
+	for password, expected := range map[string]string{
+		"vagrant":   "dgBhAGcAcgBhAG4AdABQAGEAcwBzAHcAbwByAGQA",
+		"P@ssw0rd!": "UABAAHMAcwB3ADAAcgBkACEAUABhAHMAcwB3AG8AcgBkAA==",
+		"":          "UABhAHMAcwB3AG8AcgBkAA==",
+	} {
+		actual := EncodePassword(password)
+		if actual != expected {
+			t.Errorf("Expected encoded password '%s' for '%s', but got '%s'", expected, password, actual)
+		}
+	}
+}
+
+func TestEncodeAdministratorPassword(t *testing.T) {
+	for password, expected := range map[string]string{
+		"vagrant":   "dgBhAGcAcgBhAG4AdABBAGQAbQBpAG4AaQBzAHQAcgBhAHQAbwByAFAAYQBzAHMAdwBvAHIAZAA=",
+		"Pässwörd€": "UADkAHMAcwB3APYAcgBkAKwgQQBkAG0AaQBuAGkAcwB0AHIAYQB0AG8AcgBQAGEAcwBzAHcAbwByAGQA",
+	} {
+		actual := EncodeAdministratorPassword(password)
+		if actual != expected {
+			t.Errorf("Expected encoded administrator password '%s' for '%s', but got '%s'", expected, password, actual)
+		}
+	}
+}
+
+func TestDefault(t *testing.T) {
+	for _, value := range []interface{}{nil, "", 0, uint32(0), 0.0, false, []string{}, map[string]interface{}{}} {
+		if actual := Default("UTC", value); actual != "UTC" {
+			t.Errorf("Expected the default for %#v, but got '%v'", value, actual)
+		}
+	}
+	for _, value := range []interface{}{"GMT", 1, true, []string{"a"}} {
+		if actual := Default("UTC", value); !reflect.DeepEqual(actual, value) {
+			t.Errorf("Expected %#v, but got '%v'", value, actual)
+		}
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	if actual := Coalesce(nil, "", "proxy", "other"); actual != "proxy" {
+		t.Errorf("Expected the first non-empty value 'proxy', but got '%v'", actual)
+	}
+	if actual := Coalesce(nil, ""); actual != nil {
+		t.Errorf("Expected nil when every value is empty, but got '%v'", actual)
+	}
+}
+
+func TestRequired(t *testing.T) {
+	if actual, err := Required("vars.domain must be set", "example.com"); err != nil || actual != "example.com" {
+		t.Errorf("Expected 'example.com', but got '%v' %v", actual, err)
+	}
+	_, err := Required("vars.domain must be set", "")
+	if err == nil || err.Error() != "vars.domain must be set" {
+		t.Errorf("Expected the required message error, but got %v", err)
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	actual, err := ToJSON(map[string]interface{}{"urls": []string{"a", "b"}, "ram": 1024})
+	if err != nil || actual != `{"ram":1024,"urls":["a","b"]}` {
+		t.Errorf("Expected the JSON object, but got '%s' %v", actual, err)
+	}
+}
+
+func TestEscapeJSON(t *testing.T) {
+	actual := EscapeJSON("C:\\Windows \"quoted\"\n")
+	if actual != `C:\\Windows \"quoted\"\n` {
+		t.Errorf("Expected the escaped JSON string, but got '%s'", actual)
+	}
+}
+
+func TestEscapeXML(t *testing.T) {
+	actual, err := EscapeXML(`P&ss<w>rd"`)
+	if err != nil || actual != "P&amp;ss&lt;w&gt;rd&#34;" {
+		t.Errorf("Expected the escaped XML text, but got '%s' %v", actual, err)
+	}
+}
+
+func TestIndent(t *testing.T) {
+	if actual := Indent(2, "a\n\nb"); actual != "  a\n\n  b" {
+		t.Errorf("Expected the non-empty lines indented, but got %q", actual)
+	}
+	if actual := NIndent(2, "a"); actual != "\n  a" {
+		t.Errorf("Expected a new line and the indented line, but got %q", actual)
+	}
+}
+
+func TestSplitAndJoin(t *testing.T) {
+	parts := Split(",", "IIS,DotNet35")
+	if !reflect.DeepEqual(parts, []string{"IIS", "DotNet35"}) {
+		t.Errorf("Expected the split parts, but got %#v", parts)
+	}
+	actual, err := Join(";", []interface{}{"IIS", 35, true})
+	if err != nil || actual != "IIS;35;true" {
+		t.Errorf("Expected the joined items, but got '%s' %v", actual, err)
+	}
+	if _, err = Join(";", "IIS"); err == nil || err.Error() != "Join expects a list, got string" {
+		t.Errorf("Expected a list error, but got %v", err)
+	}
+}
+
+func TestListAndDict(t *testing.T) {
+	if list := List("a", 1); !reflect.DeepEqual(list, []interface{}{"a", 1}) {
+		t.Errorf("Expected the list items, but got %#v", list)
+	}
+	dict, err := Dict("size", 40960, "index", 0)
+	if err != nil || !reflect.DeepEqual(dict, map[string]interface{}{"size": 40960, "index": 0}) {
+		t.Errorf("Expected the dict, but got %#v %v", dict, err)
+	}
+	if _, err = Dict("size"); err == nil || err.Error() != "Dict expects key value pairs" {
+		t.Errorf("Expected a key value pairs error, but got %v", err)
+	}
+	if _, err = Dict(1, "size"); err == nil || err.Error() != "Dict keys must be strings, got int" {
+		t.Errorf("Expected a key type error, but got %v", err)
+	}
+}
+
+func TestBase64(t *testing.T) {
+	encoded := Base64Encode("inductor")
+	if encoded != "aW5kdWN0b3I=" {
+		t.Errorf("Expected 'aW5kdWN0b3I=', but got '%s'", encoded)
+	}
+	decoded, err := Base64Decode(encoded)
+	if err != nil || decoded != "inductor" {
+		t.Errorf("Expected 'inductor', but got '%s' %v", decoded, err)
+	}
+	if _, err = Base64Decode("not base64!"); err == nil {
+		t.Errorf("Expected an error decoding invalid base64")
+	}
+}
+
+func TestSha256(t *testing.T) {
+	actual := Sha256("inductor")
+	if actual != "64670336ec9b54440a289b49d2840f3c0b28ec586abb60117f90c3223b6aa06d" {
+		t.Errorf("Expected the SHA-256 of 'inductor', but got '%s'", actual)
+	}
+}
+
+func TestUUID(t *testing.T) {
+	pattern := regexp.MustCompile("^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$")
+	first, err := UUID()
+	if err != nil || !pattern.MatchString(first) {
+		t.Errorf("Expected a version 4 UUID, but got '%s' %v", first, err)
+	}
+	second, _ := UUID()
+	if first == second {
+		t.Errorf("Expected different random UUIDs, but got '%s' twice", first)
+	}
+}
+
+func TestUUIDFromName(t *testing.T) {
+	actual := UUIDFromName("windows2012r2")
+	if actual != "ee8266c0-9c7d-5ec3-b0ac-6b20adea230e" {
+		t.Errorf("Expected the version 5 URL UUID of 'windows2012r2', but got '%s'", actual)
+	}
+}
+
+func TestRegex(t *testing.T) {
+	if ok, err := RegexMatch("^windows20(08|12)", "windows2012r2"); err != nil || !ok {
+		t.Errorf("Expected the regex to match, but got %v %v", ok, err)
+	}
+	actual, err := RegexReplace("windows(\\d+)r2", "Server $1 R2", "windows2012r2")
+	if err != nil || actual != "Server 2012 R2" {
+		t.Errorf("Expected 'Server 2012 R2', but got '%s' %v", actual, err)
+	}
+	if _, err = RegexMatch("(", "windows"); err == nil {
+		t.Errorf("Expected an error for an invalid regex")
+	}
+}
+
+func TestSizes(t *testing.T) {
+	if actual, err := GBToMB(40); err != nil || actual != "40960" {
+		t.Errorf("Expected 40960, but got %v %v", actual, err)
+	}
+	if actual, err := GBToMB(1.5); err != nil || actual != "1536" {
+		t.Errorf("Expected 1536, but got %v %v", actual, err)
+	}
+	if actual, err := GBToMB(1000); err != nil || actual != "1024000" {
+		t.Errorf("Expected 1024000, but got %v %v", actual, err)
+	}
+	if actual, err := MBToGB(uint32(40960)); err != nil || actual != "40" {
+		t.Errorf("Expected 40, but got %v %v", actual, err)
+	}
+	if actual, err := MBToGB(1536); err != nil || actual != "1.5" {
+		t.Errorf("Expected 1.5, but got %v %v", actual, err)
+	}
+	if _, err := MBToGB("40960"); err == nil || err.Error() != "Expected a number, got string" {
+		t.Errorf("Expected a number error, but got %v", err)
+	}
+}
+
+func TestSizesInTemplates(t *testing.T) {
+	tmpl := template.Must(template.New("sizes").Funcs(templateFuncs).Parse(`{{GBToMB 1000}} {{GBToMB .DiskSize}} {{MBToGB 2048000}}`))
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, &RenderOptions{DiskSize: 4000}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "1024000 4096000 2000" {
+		t.Errorf("Expected the sizes without exponents, but got %q", out.String())
+	}
+}
+
+func TestTemplateFuncsInTemplates(t *testing.T) {
+	source := `{{.Vars.timezone | Default "UTC"}} {{GBToMB 40}} {{Join "," (Split ";" "a;b")}}` +
+		`{{with Dict "name" .OSName}} {{.name | ToUpper}}{{end}}{{Indent 2 "x" | NIndent 2}}`
+	tmpl, err := template.New("funcs").Funcs(templateFuncs).Parse(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	opts := &RenderOptions{OSName: "windows2012r2", Vars: map[string]interface{}{}}
+	if err = tmpl.Execute(&out, opts); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "UTC 40960 a,b WINDOWS2012R2\n    x" {
+		t.Errorf("Expected the functions to render, but got %q", out.String())
+	}
+}
+
+func TestRequiredFailsTheRender(t *testing.T) {
+	tmpl := template.Must(template.New("funcs").Funcs(templateFuncs).Parse(`{{.Vars.domain | Required "vars.domain must be set"}}`))
+	err := tmpl.Execute(&bytes.Buffer{}, &RenderOptions{Vars: map[string]interface{}{}})
+	if err == nil || !strings.Contains(err.Error(), "vars.domain must be set") {
+		t.Errorf("Expected the required message error, but got %v", err)
+	}
+}
@@ -15,18 +15,24 @@ type FakeTemplater struct {
 	fullPathReturns     struct {
 		result1 string
 	}
+	NameStub        func() string
+	nameMutex       sync.RWMutex
+	nameArgsForCall []struct{}
+	nameReturns     struct {
+		result1 string
+	}
 	BaseFilenameStub        func() string
 	baseFilenameMutex       sync.RWMutex
 	baseFilenameArgsForCall []struct{}
 	baseFilenameReturns     struct {
 		result1 string
 	}
-	ContentStub        func(buffer io.Writer) error
-	contentMutex       sync.RWMutex
-	contentArgsForCall []struct {
+	SourceStub        func(buffer io.Writer) error
+	sourceMutex       sync.RWMutex
+	sourceArgsForCall []struct {
 		buffer io.Writer
 	}
-	contentReturns struct {
+	sourceReturns struct {
 		result1 error
 	}
 	FindTemplateStub        func(path string) tpl.Templater
@@ -69,6 +75,30 @@ func (fake *FakeTemplater) FullPathReturns(result1 string) {
 	}{result1}
 }
 
+func (fake *FakeTemplater) Name() string {
+	fake.nameMutex.Lock()
+	fake.nameArgsForCall = append(fake.nameArgsForCall, struct{}{})
+	fake.nameMutex.Unlock()
+	if fake.NameStub != nil {
+		return fake.NameStub()
+	} else {
+		return fake.nameReturns.result1
+	}
+}
+
+func (fake *FakeTemplater) NameCallCount() int {
+	fake.nameMutex.RLock()
+	defer fake.nameMutex.RUnlock()
+	return len(fake.nameArgsForCall)
+}
+
+func (fake *FakeTemplater) NameReturns(result1 string) {
+	fake.NameStub = nil
+	fake.nameReturns = struct {
+		result1 string
+	}{result1}
+}
+
 func (fake *FakeTemplater) BaseFilename() string {
 	fake.baseFilenameMutex.Lock()
 	fake.baseFilenameArgsForCall = append(fake.baseFilenameArgsForCall, struct{}{})
@@ -93,34 +123,34 @@ func (fake *FakeTemplater) BaseFilenameReturns(result1 string) {
 	}{result1}
 }
 
-func (fake *FakeTemplater) Content(buffer io.Writer) error {
-	fake.contentMutex.Lock()
-	fake.contentArgsForCall = append(fake.contentArgsForCall, struct {
+func (fake *FakeTemplater) Source(buffer io.Writer) error {
+	fake.sourceMutex.Lock()
+	fake.sourceArgsForCall = append(fake.sourceArgsForCall, struct {
 		buffer io.Writer
 	}{buffer})
-	fake.contentMutex.Unlock()
-	if fake.ContentStub != nil {
-		return fake.ContentStub(buffer)
+	fake.sourceMutex.Unlock()
+	if fake.SourceStub != nil {
+		return fake.SourceStub(buffer)
 	} else {
-		return fake.contentReturns.result1
+		return fake.sourceReturns.result1
 	}
 }
 
-func (fake *FakeTemplater) ContentCallCount() int {
-	fake.contentMutex.RLock()
-	defer fake.contentMutex.RUnlock()
-	return len(fake.contentArgsForCall)
+func (fake *FakeTemplater) SourceCallCount() int {
+	fake.sourceMutex.RLock()
+	defer fake.sourceMutex.RUnlock()
+	return len(fake.sourceArgsForCall)
 }
 
-func (fake *FakeTemplater) ContentArgsForCall(i int) io.Writer {
-	fake.contentMutex.RLock()
-	defer fake.contentMutex.RUnlock()
-	return fake.contentArgsForCall[i].buffer
+func (fake *FakeTemplater) SourceArgsForCall(i int) io.Writer {
+	fake.sourceMutex.RLock()
+	defer fake.sourceMutex.RUnlock()
+	return fake.sourceArgsForCall[i].buffer
 }
 
-func (fake *FakeTemplater) ContentReturns(result1 error) {
-	fake.ContentStub = nil
-	fake.contentReturns = struct {
+func (fake *FakeTemplater) SourceReturns(result1 error) {
+	fake.SourceStub = nil
+	fake.sourceReturns = struct {
 		result1 error
 	}{result1}
 }
@@ -12,26 +12,26 @@ import (
 // Template represents a path to a template file
 type template struct {
 	path             string
+	name             string
 	partialTemplates []Templater
 }
 
 // NewRootTemplate create a new RootTemplate instance complete with partial templates
-func NewRootTemplate(path, osName string) Templater {
-	rootTemplate := &template{}
-	rootTemplate.path = path
+func NewRootTemplate(baseDir, path, osName string) Templater {
+	rootTemplate := newTemplate(baseDir, path)
 	partials := make(map[string]*template)
 
 	// get all shared partial templates
 	partialFiles := listPartialTemplatesFn(rootTemplate.Dir(), rootTemplate.BaseFilename())
 	for _, f := range partialFiles {
-		pt := &template{path: f}
+		pt := newTemplate(baseDir, f)
 		partials[pt.Filename()] = pt
 	}
 
 	// get all OS specific partial templates, overwriting any non-specific templates
 	partialFilesOS := listPartialTemplatesOSSpecificFn(rootTemplate.Dir(), rootTemplate.BaseFilename(), osName)
 	for _, f := range partialFilesOS {
-		pt := &template{path: f}
+		pt := newTemplate(baseDir, f)
 		partials[pt.Filename()] = pt
 	}
 
@@ -48,11 +48,28 @@ func NewRootTemplate(path, osName string) Templater {
 	return rootTemplate
 }
 
+func newTemplate(baseDir, path string) *template {
+	name, err := filepath.Rel(baseDir, path)
+	if err != nil {
+		name = path
+	}
+	return &template{
+		path: path,
+		name: filepath.ToSlash(name),
+	}
+}
+
 // FullPath returns the full path to the template file
 func (t *template) FullPath() string {
 	return t.path
 }
 
+// Name is the path of the template file relative to the base dir, used to
+// identify the template in error messages
+func (t *template) Name() string {
+	return t.name
+}
+
 // BaseFilename is the name of the file minus the file extension
 func (t *template) BaseFilename() string {
 	ext := filepath.Ext(t.path)
@@ -72,41 +89,21 @@ func (t *template) Filename() string {
 	return file
 }
 
-// Content of this template and all of its partial templates
-func (t *template) Content(buffer io.Writer) error {
-	// write root template
+// Source of this template file only, excluding any partial templates
+func (t *template) Source(buffer io.Writer) error {
 	tpl, err := ioutil.ReadFile(t.path)
 	if err != nil {
 		return err
 	}
-
 	_, err = buffer.Write(tpl)
-	if err != nil {
-		return err
-	}
-
-	// write each partial template
-	for _, pt := range t.partialTemplates {
-		// wrap the partial template define statement
-		defineName := strings.TrimPrefix(pt.BaseFilename(), t.BaseFilename())
-		defineName = strings.Replace(defineName, ".", "", -1)
-		_, err = buffer.Write([]byte(fmt.Sprintf("\n{{define \"%s\"}}\n", defineName)))
-		if err != nil {
-			return err
-		}
-
-		err := pt.Content(buffer)
-		if err != nil {
-			return err
-		}
-
-		_, err = buffer.Write([]byte("\n{{end}}"))
-		if err != nil {
-			return err
-		}
-	}
+	return err
+}
 
-	return nil
+// PartialName is the name a root template uses to include the partial, e.g.
+// the Autounattend.xml.disks.partial is included with {{template "disks" .}}
+func PartialName(root, partial Templater) string {
+	name := strings.TrimPrefix(partial.BaseFilename(), root.BaseFilename())
+	return strings.Replace(name, ".", "", -1)
 }
 
 // FindTemplate finds the root template by path if it exists
@@ -5,8 +5,9 @@ import "io"
 // Templater can render a specific template and its partials
 type Templater interface {
 	FullPath() string
+	Name() string
 	BaseFilename() string
-	Content(buffer io.Writer) error
+	Source(buffer io.Writer) error
 	FindTemplate(path string) Templater
 	ListTemplates() []Templater
 }
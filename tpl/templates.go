@@ -22,7 +22,7 @@ func New(baseDir, osName string) TemplateContainer {
 	// find all root templates
 	entries := listRootTemplatesFn(baseDir)
 	for _, e := range entries {
-		rootTemplate := NewRootTemplate(e, osName)
+		rootTemplate := NewRootTemplate(baseDir, e, osName)
 		templates.all = append(templates.all, rootTemplate)
 	}
 
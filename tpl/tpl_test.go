@@ -67,18 +67,6 @@ var _ = Describe("Tpl", func() {
 				path := filepath.Join(tmpDir, "Autounattend.xml.disks.partial")
 				Expect(rootTemplate.FindTemplate(path)).ToNot(BeNil())
 			})
-			It("should have template content", func() {
-				expected := `Autounattend.xml.template
-{{define "disks"}}
-Autounattend.xml.disks.partial
-{{end}}
-{{define "oobe"}}
-Autounattend.xml.oobe.partial
-{{end}}`
-				var buffer bytes.Buffer
-				Expect(rootTemplate.Content(&buffer)).NotTo(HaveOccurred())
-				Expect(buffer.String()).To(Equal(expected))
-			})
 		})
 	})
 
@@ -105,6 +93,20 @@ Autounattend.xml.oobe.partial
 				path := filepath.Join(tmpDir, "nano/Autounattend.xml.disks.partial")
 				Expect(rootTemplate.FindTemplate(path)).ToNot(BeNil())
 			})
+			It("should name the templates relative to the base dir", func() {
+				Expect(rootTemplate.Name()).To(Equal("Autounattend.xml.template"))
+				path := filepath.Join(tmpDir, "nano/Autounattend.xml.disks.partial")
+				Expect(rootTemplate.FindTemplate(path).Name()).To(Equal("nano/Autounattend.xml.disks.partial"))
+			})
+			It("should have the partial name used to include the partial", func() {
+				path := filepath.Join(tmpDir, "nano/Autounattend.xml.disks.partial")
+				Expect(tpl.PartialName(rootTemplate, rootTemplate.FindTemplate(path))).To(Equal("disks"))
+			})
+			It("should have source content without the partials", func() {
+				var buffer bytes.Buffer
+				Expect(rootTemplate.Source(&buffer)).NotTo(HaveOccurred())
+				Expect(buffer.String()).To(Equal("Autounattend.xml.template"))
+			})
 		})
 
 		Describe("packer.json.template root template", func() {